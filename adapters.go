@@ -1,5 +1,10 @@
 package gengen
 
+import (
+	"reflect"
+	"sort"
+)
+
 type SliceAdapter[T any] struct {
 	slice []T
 	index int
@@ -31,20 +36,48 @@ func (s *SliceAdapter[T]) Error() error {
 	return nil
 }
 
+type sliceAdapterState[T any] struct {
+	Slice []T
+	Index int
+}
+
+func (s *SliceAdapter[T]) GobEncode() ([]byte, error) {
+	return EncodeSnapshot(sliceAdapterState[T]{Slice: s.slice, Index: s.index})
+}
+
+func (s *SliceAdapter[T]) GobDecode(snapshot []byte) error {
+	var state sliceAdapterState[T]
+	if err := DecodeSnapshot(snapshot, &state); err != nil {
+		return err
+	}
+	s.slice, s.index = state.Slice, state.Index
+	return nil
+}
+
+// MapAdapter iterates over a map the same way a range statement does.
+// The map is not copied, so entries that are deleted before they are reached are not produced,
+// and entries that are added during the iteration may or may not be produced.
+// Values are read when their entry is reached.
 type MapAdapter[K comparable, V any] struct {
-	items []Pair[K, V]
-	index int
+	iter  *reflect.MapIter
+	done  bool
+	key   K
+	value V
 }
 
 func (m *MapAdapter[K, V]) Next() bool {
-	m.index++
-	return m.index < len(m.items)
+	// reflect.MapIter panics when advanced after it is exhausted.
+	if m.done || !m.iter.Next() {
+		m.done = true
+		return false
+	}
+	reflect.ValueOf(&m.key).Elem().SetIterKey(m.iter)
+	reflect.ValueOf(&m.value).Elem().SetIterValue(m.iter)
+	return true
 }
 
 func (m *MapAdapter[K, V]) Value() (K, V) {
-	item := m.items[m.index]
-	k, v := item.first, item.second
-	return k, v
+	return m.key, m.value
 }
 
 func (m *MapAdapter[K, V]) Error() error {
@@ -52,9 +85,94 @@ func (m *MapAdapter[K, V]) Error() error {
 }
 
 func NewMapAdapter[K comparable, V any](map_ map[K]V) *MapAdapter[K, V] {
-	items := make([]Pair[K, V], 0, len(map_))
-	for key, value := range map_ {
-		items = append(items, *NewPair(key, value))
+	return &MapAdapter[K, V]{iter: reflect.ValueOf(map_).MapRange()}
+}
+
+// Ordered is the set of types that can be sorted using the < operator.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+// SortedMapAdapter iterates over a map in the order of its keys, instead of Go's randomized map order.
+// The keys are sorted when the iteration starts, so entries that are added during the iteration
+// are not produced.
+// Like in a range statement, entries that are deleted before they are reached are not produced,
+// and values are read when their entry is reached.
+type SortedMapAdapter[K comparable, V any] struct {
+	map_  map[K]V
+	keys  []K
+	index int
+	value V
+}
+
+func (m *SortedMapAdapter[K, V]) Next() bool {
+	for m.index++; m.index < len(m.keys); m.index++ {
+		value, exists := m.map_[m.keys[m.index]]
+		if exists {
+			m.value = value
+			return true
+		}
+	}
+	return false
+}
+
+func (m *SortedMapAdapter[K, V]) Value() (K, V) {
+	return m.keys[m.index], m.value
+}
+
+func (m *SortedMapAdapter[K, V]) Error() error {
+	return nil
+}
+
+type sortedMapAdapterState[K comparable, V any] struct {
+	Map   map[K]V
+	Keys  []K
+	Index int
+	Value V
+}
+
+func (m *SortedMapAdapter[K, V]) GobEncode() ([]byte, error) {
+	return EncodeSnapshot(sortedMapAdapterState[K, V]{Map: m.map_, Keys: m.keys, Index: m.index, Value: m.value})
+}
+
+func (m *SortedMapAdapter[K, V]) GobDecode(snapshot []byte) error {
+	var state sortedMapAdapterState[K, V]
+	if err := DecodeSnapshot(snapshot, &state); err != nil {
+		return err
 	}
-	return &MapAdapter[K, V]{items: items, index: -1}
+	m.map_, m.keys, m.index, m.value = state.Map, state.Keys, state.Index, state.Value
+	return nil
+}
+
+// NewSortedMapAdapter creates a SortedMapAdapter ordering the keys using the < operator.
+func NewSortedMapAdapter[K Ordered, V any](map_ map[K]V) *SortedMapAdapter[K, V] {
+	return NewSortedMapAdapterFunc(map_, func(a, b K) bool { return a < b })
+}
+
+// NewSortedMapAdapterFunc creates a SortedMapAdapter ordering the keys using the less function.
+func NewSortedMapAdapterFunc[K comparable, V any](map_ map[K]V, less func(a, b K) bool) *SortedMapAdapter[K, V] {
+	keys := make([]K, 0, len(map_))
+	for key := range map_ {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return &SortedMapAdapter[K, V]{map_: map_, keys: keys, index: -1}
+}
+
+// Sorted is used in generator-definitions to range over a map in the order of its keys:
+//
+//	for key, value := range gengen.Sorted(m) {
+//		gengen.Yield(key)
+//	}
+//
+// In normal Go code it returns the map as is.
+func Sorted[K Ordered, V any](map_ map[K]V) map[K]V {
+	return map_
+}
+
+// SortedFunc is like Sorted, but orders the keys using the less function.
+func SortedFunc[K comparable, V any](map_ map[K]V, less func(a, b K) bool) map[K]V {
+	return map_
 }
@@ -65,3 +65,96 @@ func TestNewMapAdaptor(t *testing.T) {
 		})
 	}
 }
+
+func Keys[K comparable, V any](gen Iterator2[K, V]) (keys []K) {
+	for gen.Next() {
+		keys = append(keys, First(gen.Value()))
+	}
+	return
+}
+
+func TestNewSortedMapAdapter(t *testing.T) {
+	tests := []struct {
+		name string
+		m    map[int]string
+		want []int
+	}{
+		{"empty", map[int]string{}, nil},
+		{"single", map[int]string{1: "a"}, []int{1}},
+		{"multiple", map[int]string{3: "c", 1: "a", 2: "b", 5: "e", 4: "d"}, []int{1, 2, 3, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Keys[int, string](NewSortedMapAdapter(tt.m)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+			if got := ToMap[int, string](NewSortedMapAdapter(tt.m)); !reflect.DeepEqual(got, tt.m) {
+				t.Errorf("got = %v, want %v", got, tt.m)
+			}
+		})
+	}
+}
+
+func TestNewSortedMapAdapterFunc(t *testing.T) {
+	m := map[string]int{"bb": 2, "a": 1, "ccc": 3}
+	want := []string{"ccc", "bb", "a"}
+	longestFirst := func(a, b string) bool { return len(a) > len(b) }
+	if got := Keys[string, int](NewSortedMapAdapterFunc(m, longestFirst)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestMapAdapterDeletions(t *testing.T) {
+	m := map[int]string{1: "a", 2: "b", 3: "c", 4: "d"}
+	adapter := NewMapAdapter(m)
+	if !adapter.Next() {
+		t.Fatal("expected an entry")
+	}
+	first, _ := adapter.Value()
+	for key := range m {
+		if key != first {
+			delete(m, key)
+		}
+	}
+	if adapter.Next() {
+		key, value := adapter.Value()
+		t.Errorf("got deleted entry %v: %v", key, value)
+	}
+}
+
+func TestMapAdapterUpdates(t *testing.T) {
+	m := map[int]int{1: 0, 2: 0, 3: 0}
+	var got []int
+	adapter := NewMapAdapter(m)
+	for adapter.Next() {
+		_, value := adapter.Value()
+		got = append(got, value)
+		for key := range m {
+			m[key]++
+		}
+	}
+	// Values are read when reached, so each one sees the updates made before it.
+	want := []int{0, 1, 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if adapter.Next() {
+		t.Error("Next() = true after exhaustion")
+	}
+}
+
+func TestSortedMapAdapterDeletions(t *testing.T) {
+	m := map[int]string{1: "a", 2: "b", 3: "c", 4: "d"}
+	var got []int
+	adapter := NewSortedMapAdapter(m)
+	for adapter.Next() {
+		key, _ := adapter.Value()
+		got = append(got, key)
+		delete(m, key+1)
+		m[key+10] = "new"
+	}
+	want := []int{1, 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package benchmarks
+
+import (
+	"github.com/tmr232/gengen"
+	"reflect"
+	"testing"
+)
+
+func drain(gen gengen.Generator[int]) (sum int) {
+	for gen.Next() {
+		sum += gen.Value()
+	}
+	return sum
+}
+
+func toSlice(gen gengen.Generator[int]) (slice []int) {
+	for gen.Next() {
+		slice = append(slice, gen.Value())
+	}
+	return slice
+}
+
+var values = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+func TestStructsMatchClosures(t *testing.T) {
+	tests := []struct {
+		name    string
+		closure gengen.Generator[int]
+		structs gengen.Generator[int]
+	}{
+		{"Range", Range(10), RangeStruct(10)},
+		{"Fibonacci", Fibonacci(10), FibonacciStruct(10)},
+		{"EvenValues", EvenValues(values), EvenValuesStruct(values)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := toSlice(tt.closure)
+			if got := toSlice(tt.structs); !reflect.DeepEqual(got, want) {
+				t.Errorf("%sStruct() = %v, want %v", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkRange(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(Range(100))
+	}
+}
+
+func BenchmarkRangeStruct(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(RangeStruct(100))
+	}
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(Fibonacci(50))
+	}
+}
+
+func BenchmarkFibonacciStruct(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(FibonacciStruct(50))
+	}
+}
+
+func BenchmarkEvenValues(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(EvenValues(values))
+	}
+}
+
+func BenchmarkEvenValuesStruct(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(EvenValuesStruct(values))
+	}
+}
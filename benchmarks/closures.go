@@ -0,0 +1,36 @@
+//go:build gengen
+
+package benchmarks
+
+import (
+	"github.com/tmr232/gengen"
+)
+
+//go:generate go run github.com/tmr232/gengen/cmd/gengen
+
+// The generators in this file use the default closure-based state machine.
+
+func Range(stop int) gengen.Generator[int] {
+	for i := 0; i < stop; i++ {
+		gengen.Yield(i)
+	}
+	return nil
+}
+
+func Fibonacci(n int) gengen.Generator[int] {
+	a, b := 1, 1
+	for i := 0; i < n; i++ {
+		gengen.Yield(a)
+		a, b = b, a+b
+	}
+	return nil
+}
+
+func EvenValues(values []int) gengen.Generator[int] {
+	for _, value := range values {
+		if value%2 == 0 {
+			gengen.Yield(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build gengen
+
+package benchmarks
+
+import (
+	"github.com/tmr232/gengen"
+)
+
+// The generators in this file are the same as in closures.go, using struct-based state machines.
+
+//gengen:struct
+func RangeStruct(stop int) gengen.Generator[int] {
+	for i := 0; i < stop; i++ {
+		gengen.Yield(i)
+	}
+	return nil
+}
+
+//gengen:struct
+func FibonacciStruct(n int) gengen.Generator[int] {
+	a, b := 1, 1
+	for i := 0; i < n; i++ {
+		gengen.Yield(a)
+		a, b = b, a+b
+	}
+	return nil
+}
+
+//gengen:struct
+func EvenValuesStruct(values []int) gengen.Generator[int] {
+	for _, value := range values {
+		if value%2 == 0 {
+			gengen.Yield(value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package gengen
+
+import "errors"
+
+// ErrNoClones is returned when cloning a generator that was not generated in clone mode.
+var ErrNoClones = errors.New("gengen: generator does not support cloning")
+
+// Cloner is implemented by iterators that can be forked, to continue the iteration from the same
+// point independently.
+type Cloner[T any] interface {
+	Clone() Iterator[T]
+}
+
+// ClonePointer returns a pointer to a copy of the value a pointer points to, or nil for nil pointers.
+// Used by code-generation, and should not generally be used manually.
+func ClonePointer[T any](pointer *T) *T {
+	if pointer == nil {
+		return nil
+	}
+	clone := *pointer
+	return &clone
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+var exprType = reflect.TypeOf((*ast.Expr)(nil)).Elem()
+var objectType = reflect.TypeOf((*ast.Object)(nil))
+var scopeType = reflect.TypeOf((*ast.Scope)(nil))
+
+// cloneAst returns a deep copy of node.
+// Expressions for which rewrite returns a non-nil expression are replaced with that expression,
+// as long as they are in a position that accepts any expression.
+func cloneAst[T ast.Node](node T, rewrite func(expr ast.Expr) ast.Expr) T {
+	return cloneValue(reflect.ValueOf(node), rewrite).Interface().(T)
+}
+
+func cloneValue(value reflect.Value, rewrite func(expr ast.Expr) ast.Expr) reflect.Value {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		if value.Type() == objectType || value.Type() == scopeType {
+			// Objects and scopes are the parser's name resolution, which we don't need to copy.
+			return reflect.Zero(value.Type())
+		}
+		clone := reflect.New(value.Type().Elem())
+		clone.Elem().Set(cloneValue(value.Elem(), rewrite))
+		return clone
+	case reflect.Struct:
+		clone := reflect.New(value.Type()).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			clone.Field(i).Set(cloneSlot(value.Field(i), rewrite))
+		}
+		return clone
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		clone := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			clone.Index(i).Set(cloneSlot(value.Index(i), rewrite))
+		}
+		return clone
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		return cloneValue(value.Elem(), rewrite)
+	default:
+		return value
+	}
+}
+
+// cloneSlot clones a value stored in a struct field or a slice element, rewriting
+// expressions stored in ast.Expr slots.
+func cloneSlot(value reflect.Value, rewrite func(expr ast.Expr) ast.Expr) reflect.Value {
+	if value.Type() == exprType && !value.IsNil() {
+		if replacement := rewrite(value.Interface().(ast.Expr)); replacement != nil {
+			expr := reflect.New(exprType).Elem()
+			expr.Set(reflect.ValueOf(replacement))
+			return expr
+		}
+	}
+	clone := cloneValue(value, rewrite)
+	if value.Kind() == reflect.Interface && clone.IsValid() && clone.Type() != value.Type() {
+		// Store the concrete clone back in an interface of the slot's type.
+		slot := reflect.New(value.Type()).Elem()
+		slot.Set(clone)
+		return slot
+	}
+	return clone
+}
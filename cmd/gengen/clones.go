@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+)
+
+// The directive requesting a generator that can be cloned.
+const cloneDirective = "//gengen:clone"
+
+// checkClone returns diagnostics for all the state of a generator that cannot be copied when
+// cloning it, as the clone would share its iteration with the original generator: nested
+// iterators, and the adapters of loops ranging over maps in Go's iteration order.
+func (wiz *FuncWizard) checkClone() []string {
+	var diagnostics []string
+	report := func(node interface{ Pos() token.Pos }, message string) {
+		diagnostics = append(diagnostics, fmt.Sprintf("%s: %s", wiz.pkg.Fset.Position(node.Pos()), message))
+	}
+	qualifier := wiz.diagnosticQualifier()
+
+	for _, obj := range wiz.stateVariables() {
+		if iterator := sharedIterator(obj.Type(), nil); iterator != nil {
+			report(obj, fmt.Sprintf("%s cannot be cloned: %s would be shared by the clones", obj.Name(), types.TypeString(iterator, qualifier)))
+		}
+	}
+	for _, node := range wiz.yieldingMapRanges() {
+		report(node, "map iteration cannot be cloned, range over gengen.Sorted(m) instead")
+	}
+	return diagnostics
+}
+
+// sharedIterator returns the iterator type contained in the values of a type, whose iteration
+// would be shared by copies of those values, or nil if there is none.
+// Iterators are generators and any other type with the methods of gengen.Iterator, including
+// interfaces holding them.
+func sharedIterator(typ types.Type, checked []types.Type) types.Type {
+	for _, checkedType := range checked {
+		if types.Identical(typ, checkedType) {
+			// Recursive types are checked once.
+			return nil
+		}
+	}
+	if isIterator(typ) {
+		return typ
+	}
+	switch typ := typ.(type) {
+	case *types.Named:
+		if obj := typ.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == GeneratorType.PkgPath && obj.Name() == GeneratorType.Name {
+			return typ
+		}
+		return sharedIterator(typ.Underlying(), append(checked, typ))
+	case *types.Pointer:
+		return sharedIterator(typ.Elem(), checked)
+	case *types.Slice:
+		return sharedIterator(typ.Elem(), checked)
+	case *types.Array:
+		return sharedIterator(typ.Elem(), checked)
+	case *types.Map:
+		return sharedIterator(typ.Elem(), checked)
+	case *types.Struct:
+		for i := 0; i < typ.NumFields(); i++ {
+			if iterator := sharedIterator(typ.Field(i).Type(), checked); iterator != nil {
+				return iterator
+			}
+		}
+	}
+	return nil
+}
+
+// isIterator checks whether the method set of a type has the methods of gengen.Iterator, or of
+// gengen.Iterator2.
+func isIterator(typ types.Type) bool {
+	methods := types.NewMethodSet(typ)
+	results := func(name string) *types.Tuple {
+		method := methods.Lookup(nil, name)
+		if method == nil {
+			return nil
+		}
+		signature := method.Type().(*types.Signature)
+		if signature.Params().Len() != 0 {
+			return nil
+		}
+		return signature.Results()
+	}
+	next, value, err := results("Next"), results("Value"), results("Error")
+	return next != nil && next.Len() == 1 && types.Identical(next.At(0).Type(), types.Typ[types.Bool]) &&
+		value != nil && value.Len() > 0 &&
+		err != nil && err.Len() == 1 && types.Identical(err.At(0).Type(), types.Universe.Lookup("error").Type())
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckClone(t *testing.T) {
+	diagnostics := testdataDiagnostics(t, "clones", func(wiz *FuncWizard) []string {
+		return wiz.checkClone()
+	})
+
+	want := []string{
+		"clones.go:11: gen cannot be cloned: gengen.Generator[int] would be shared by the clones",
+		"clones.go:11: sources cannot be cloned: gengen.Generator[int] would be shared by the clones",
+		"clones.go:15: map iteration cannot be cloned, range over gengen.Sorted(m) instead",
+		"clones.go:49: iterator cannot be cloned: gengen.Iterator[int] would be shared by the clones",
+		"clones.go:49: peekable cannot be cloned: *gengen.Peekable[int] would be shared by the clones",
+		"clones.go:49: custom cannot be cloned: *countdown would be shared by the clones",
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("checkClone() = %q, want %q", diagnostics, want)
+	}
+}
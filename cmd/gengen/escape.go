@@ -0,0 +1,186 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// findBoxedVariables finds the variables that need a fresh copy on every loop iteration.
+//
+// In Go, every iteration of a loop gets its own copy of the variables declared in the loop,
+// including the loop variables themselves. Hoisting makes all iterations share a single variable
+// instead. This is only observable when the variable escapes the iteration - when it is captured
+// by a function literal, or its address is taken - so only those variables are boxed.
+func findBoxedVariables(info *types.Info, body *ast.BlockStmt) map[types.Object]bool {
+	declaredInLoop := make(map[types.Object]bool)
+	repeatedFrom := findBackwardGotoTarget(info, body)
+
+	var stack []ast.Node
+	inLoop := func() bool {
+		for _, node := range stack {
+			switch node.(type) {
+			case *ast.ForStmt, *ast.RangeStmt:
+				return true
+			}
+		}
+		return false
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if _, isFuncLit := node.(*ast.FuncLit); isFuncLit {
+			// Variables declared within the function literal are not hoisted.
+			return false
+		}
+		stack = append(stack, node)
+		if ident, isIdent := node.(*ast.Ident); isIdent {
+			repeated := repeatedFrom.IsValid() && ident.Pos() > repeatedFrom
+			if obj, isDef := info.Defs[ident]; isDef && obj != nil && (inLoop() || repeated) {
+				declaredInLoop[obj] = true
+			}
+		}
+		return true
+	})
+
+	boxed := make(map[types.Object]bool)
+	for obj := range findEscapingVariables(info, body) {
+		if declaredInLoop[obj] {
+			boxed[obj] = true
+		}
+	}
+	return boxed
+}
+
+// findBackwardGotoTarget returns the position of the first label targeted by a goto statement
+// following it, or token.NoPos if there is none.
+// Like in loops, the declarations following such a label may be executed more than once.
+func findBackwardGotoTarget(info *types.Info, body *ast.BlockStmt) token.Pos {
+	labels := make(map[types.Object]token.Pos)
+	var gotos []*ast.BranchStmt
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.LabeledStmt:
+			labels[info.Defs[node.Label]] = node.Pos()
+		case *ast.BranchStmt:
+			if node.Tok == token.GOTO {
+				gotos = append(gotos, node)
+			}
+		}
+		return true
+	})
+
+	target := token.NoPos
+	for _, jump := range gotos {
+		label := labels[info.Uses[jump.Label]]
+		if label < jump.Pos() && (!target.IsValid() || label < target) {
+			target = label
+		}
+	}
+	return target
+}
+
+// findEscapingVariables finds the variables that may be accessed other than by their name -
+// variables captured by function literals, or whose address is taken.
+func findEscapingVariables(info *types.Info, body *ast.BlockStmt) map[types.Object]bool {
+	escaping := make(map[types.Object]bool)
+	addressOf := func(expr ast.Expr) {
+		if ident := addressedVariable(info, expr); ident != nil {
+			if obj, isVar := info.Uses[ident].(*types.Var); isVar && isLocalVariable(obj, body) {
+				escaping[obj] = true
+			}
+		}
+	}
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.UnaryExpr:
+			if node.Op == token.AND {
+				addressOf(node.X)
+			}
+		case *ast.SliceExpr:
+			// Slicing an array takes its address
+			if _, isArray := info.TypeOf(node.X).Underlying().(*types.Array); isArray {
+				addressOf(node.X)
+			}
+		case *ast.SelectorExpr:
+			// Calling a pointer method on a variable takes its address
+			if selection, isSelection := info.Selections[node]; isSelection && selection.Kind() == types.MethodVal {
+				_, isPointerReceiver := selection.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer)
+				_, isPointer := info.TypeOf(node.X).(*types.Pointer)
+				if isPointerReceiver && !isPointer {
+					addressOf(node.X)
+				}
+			}
+		case *ast.FuncLit:
+			for obj := range capturedVariables(info, node) {
+				if isLocalVariable(obj.(*types.Var), body) {
+					escaping[obj] = true
+				}
+			}
+			return false
+		}
+		return true
+	})
+	return escaping
+}
+
+// addressedVariable returns the variable whose memory an addressable expression refers to,
+// or nil if there is none.
+// Taking the address of a field of a struct, or an element of an array, takes the address of the
+// variable holding it - unless a pointer is followed on the way.
+func addressedVariable(info *types.Info, expr ast.Expr) *ast.Ident {
+	for {
+		switch node := expr.(type) {
+		case *ast.Ident:
+			return node
+		case *ast.ParenExpr:
+			expr = node.X
+		case *ast.SelectorExpr:
+			selection, isSelection := info.Selections[node]
+			if !isSelection || selection.Kind() != types.FieldVal || selection.Indirect() {
+				return nil
+			}
+			if _, isPointer := info.TypeOf(node.X).Underlying().(*types.Pointer); isPointer {
+				return nil
+			}
+			expr = node.X
+		case *ast.IndexExpr:
+			if _, isArray := info.TypeOf(node.X).Underlying().(*types.Array); !isArray {
+				return nil
+			}
+			expr = node.X
+		default:
+			return nil
+		}
+	}
+}
+
+// capturedVariables returns the variables used in a function literal that are declared outside it.
+func capturedVariables(info *types.Info, funcLit *ast.FuncLit) map[types.Object]bool {
+	captured := make(map[types.Object]bool)
+	ast.Inspect(funcLit.Body, func(node ast.Node) bool {
+		if ident, isIdent := node.(*ast.Ident); isIdent {
+			if obj, isVar := info.Uses[ident].(*types.Var); isVar && !isWithin(obj, funcLit) {
+				captured[obj] = true
+			}
+		}
+		return true
+	})
+	return captured
+}
+
+// isLocalVariable checks whether a variable is declared within the body of the generator.
+// Package-level variables and struct fields are not a part of the state of the generator.
+func isLocalVariable(obj *types.Var, body *ast.BlockStmt) bool {
+	return !obj.IsField() && isWithin(obj, body)
+}
+
+// isWithin checks whether an object is declared within a node.
+func isWithin(obj types.Object, node ast.Node) bool {
+	return node.Pos() <= obj.Pos() && obj.Pos() < node.End()
+}
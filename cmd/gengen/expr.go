@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"reflect"
+)
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// exprKinds are all the kinds of expressions, and whether they can be converted.
+// Expressions are converted by copying them, while rewriting the identifiers and function
+// literals within them, so most kinds need no special handling.
+// Listing a type that is not an expression fails to compile, but the compiler cannot check that the
+// list is complete - only TestExprKinds does, so new kinds of expressions are caught by the tests.
+var exprKinds = map[reflect.Type]bool{}
+
+func init() {
+	for expr, supported := range map[ast.Expr]bool{
+		(*ast.ArrayType)(nil):      true,
+		(*ast.BadExpr)(nil):        false,
+		(*ast.BasicLit)(nil):       true,
+		(*ast.BinaryExpr)(nil):     true,
+		(*ast.CallExpr)(nil):       true,
+		(*ast.ChanType)(nil):       true,
+		(*ast.CompositeLit)(nil):   true,
+		(*ast.Ellipsis)(nil):       true,
+		(*ast.FuncLit)(nil):        true,
+		(*ast.FuncType)(nil):       true,
+		(*ast.Ident)(nil):          true,
+		(*ast.IndexExpr)(nil):      true,
+		(*ast.IndexListExpr)(nil):  true,
+		(*ast.InterfaceType)(nil):  true,
+		(*ast.KeyValueExpr)(nil):   true,
+		(*ast.MapType)(nil):        true,
+		(*ast.ParenExpr)(nil):      true,
+		(*ast.SelectorExpr)(nil):   true,
+		(*ast.SliceExpr)(nil):      true,
+		(*ast.StarExpr)(nil):       true,
+		(*ast.StructType)(nil):     true,
+		(*ast.TypeAssertExpr)(nil): true,
+		(*ast.UnaryExpr)(nil):      true,
+	} {
+		exprKinds[reflect.TypeOf(expr)] = supported
+	}
+}
+
+// significantPositions are the positions that change the meaning of a node when they are missing,
+// so they are kept when clearing positions.
+var significantPositions = map[reflect.Type]string{
+	reflect.TypeOf(ast.CallExpr{}): "Ellipsis",
+	reflect.TypeOf(ast.TypeSpec{}): "Assign",
+}
+
+// convertExpr converts an expression into the matching expression in the generated code.
+// The result is a new tree, and the original expression is left untouched.
+// Positions are cleared, so that the printer lays out the original and the generated parts
+// of the expression alike.
+func (wiz *FuncWizard) convertExpr(expr ast.Expr) ast.Expr {
+	converted := wiz.rewriteExpr(expr)
+	if converted == nil {
+		converted = cloneAst(expr, wiz.rewriteExpr)
+	}
+	clearPositions(reflect.ValueOf(converted))
+	return converted
+}
+
+// renderExpr converts an expression and prints it.
+func (wiz *FuncWizard) renderExpr(expr ast.Expr) string {
+	if expr == nil {
+		// This saves some work with conditionally-nil nodes.
+		return ""
+	}
+	return wiz.printNode(wiz.convertExpr(expr))
+}
+
+// rewriteExpr returns the replacement of an expression, or nil if only its children need to be
+// converted.
+func (wiz *FuncWizard) rewriteExpr(expr ast.Expr) ast.Expr {
+	if !exprKinds[reflect.TypeOf(expr)] {
+		log.Fatalf("%s: unsupported expression %T", wiz.pkg.Fset.Position(expr.Pos()), expr)
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if definition, exists := wiz.pkg.TypesInfo.Defs[expr]; exists && definition != nil {
+			return wiz.parseExpr(wiz.DeclareVariable(definition))
+		}
+		if usage, exists := wiz.pkg.TypesInfo.Uses[expr]; exists {
+			return wiz.parseExpr(wiz.GetVariable(usage))
+		}
+		return ast.NewIdent(expr.Name)
+	case *ast.FuncLit:
+		return wiz.convertFuncLit(expr)
+	}
+	return nil
+}
+
+func (wiz *FuncWizard) convertFuncLit(node *ast.FuncLit) ast.Expr {
+	// Function literals are not lowered, we only need to rename the variables they capture.
+	var captured []types.Object
+	isCaptured := make(map[types.Object]bool)
+	funcLit := cloneAst(node, func(expr ast.Expr) ast.Expr {
+		ident, isIdent := expr.(*ast.Ident)
+		if !isIdent {
+			return nil
+		}
+		obj, isUse := wiz.pkg.TypesInfo.Uses[ident]
+		if !isUse || !wiz.IsLocal(obj) || isWithin(obj, node) {
+			return nil
+		}
+		if wiz.boxed[obj] {
+			if !isCaptured[obj] {
+				isCaptured[obj] = true
+				captured = append(captured, obj)
+			}
+			return &ast.ParenExpr{X: &ast.StarExpr{X: ast.NewIdent(wiz.definitions[obj])}}
+		}
+		return wiz.parseExpr(wiz.variables[obj])
+	})
+	if len(captured) == 0 {
+		return funcLit
+	}
+
+	// Boxed variables are shared by the entire generator, so the literal must capture the copy
+	// that belongs to the current iteration.
+	capture := &ast.AssignStmt{Tok: token.DEFINE}
+	for _, obj := range captured {
+		capture.Lhs = append(capture.Lhs, ast.NewIdent(wiz.definitions[obj]))
+		capture.Rhs = append(capture.Rhs, wiz.parseExpr(wiz.variables[obj]))
+	}
+	return &ast.CallExpr{Fun: &ast.FuncLit{
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{},
+			Results: &ast.FieldList{List: []*ast.Field{{Type: funcLit.Type}}},
+		},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			capture,
+			&ast.ReturnStmt{Results: []ast.Expr{funcLit}},
+		}},
+	}}
+}
+
+// parseExpr parses an expression generated by the wizard.
+// Positions are cleared, as they don't belong to the file set of the package.
+func (wiz *FuncWizard) parseExpr(code string) ast.Expr {
+	expr, err := parser.ParseExpr(code)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clearPositions(reflect.ValueOf(expr))
+	return expr
+}
+
+// clearPositions sets all positions within a node to token.NoPos, other than the significant ones.
+func clearPositions(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !value.IsNil() {
+			clearPositions(value.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if field.Type() == posType {
+				if significantPositions[value.Type()] != value.Type().Field(i).Name {
+					field.Set(reflect.ValueOf(token.NoPos))
+				}
+			} else if field.Type() != objectType && field.Type() != scopeType {
+				clearPositions(field)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			clearPositions(value.Index(i))
+		}
+	}
+}
+
+// printNode prints a node of the generated code.
+func (wiz *FuncWizard) printNode(node ast.Node) string {
+	var code bytes.Buffer
+	err := format.Node(&code, wiz.pkg.Fset, node)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return code.String()
+}
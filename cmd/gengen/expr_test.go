@@ -0,0 +1,50 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+// TestExprKinds checks that every kind of expression in go/ast is listed in exprKinds.
+// The kinds of expressions are the types with an exprNode method, found in the go/ast sources,
+// which do not depend on the version of the toolchain.
+func TestExprKinds(t *testing.T) {
+	astPkg, err := build.Import("go/ast", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := make(map[string]bool)
+	for kind := range exprKinds {
+		listed[kind.Elem().Name()] = true
+	}
+	found := 0
+	for _, name := range astPkg.GoFiles {
+		file, err := parser.ParseFile(token.NewFileSet(), astPkg.Dir+"/"+name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range file.Decls {
+			fdecl, isFunc := decl.(*ast.FuncDecl)
+			if !isFunc || fdecl.Name.Name != "exprNode" || fdecl.Recv == nil {
+				continue
+			}
+			found++
+			recvType := fdecl.Recv.List[0].Type
+			if star, isStar := recvType.(*ast.StarExpr); isStar {
+				recvType = star.X
+			}
+			name := recvType.(*ast.Ident).Name
+			if !listed[name] {
+				t.Errorf("ast.%s is missing from exprKinds", name)
+			}
+		}
+	}
+	if found == 0 {
+		t.Errorf("No kinds of expressions found in %s", strings.Join(astPkg.GoFiles, ", "))
+	}
+}
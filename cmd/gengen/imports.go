@@ -0,0 +1,184 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"strconv"
+	"strings"
+)
+
+// FileImports manages the imports of a generated file.
+//
+// It starts with the imports of the generator source file, adds imports as the generated code
+// requires them, and finally prunes the imports the generated code does not use.
+type FileImports struct {
+	pkg *packages.Package
+	// The imports of the generated file, in order
+	imports Imports
+	// A mapping between package paths to import names
+	names map[string]string
+	// A set of all the imported names
+	importNames map[string]bool
+	// Names that cannot be used for new imports, as they are already in use in the file
+	taken map[string]bool
+}
+
+func NewFileImports(pkg *packages.Package, file *ast.File) *FileImports {
+	fileImports := &FileImports{
+		pkg:         pkg,
+		imports:     getFileImports(file),
+		names:       make(map[string]string),
+		importNames: make(map[string]bool),
+		taken:       make(map[string]bool),
+	}
+	for _, importLine := range fileImports.imports {
+		packagePath, err := strconv.Unquote(importLine.Path)
+		if err != nil {
+			continue
+		}
+		name := fileImports.packageName(packagePath)
+		if importLine.Name != nil {
+			name = *importLine.Name
+		}
+		if name == "_" {
+			continue
+		}
+		fileImports.names[packagePath] = name
+		fileImports.importNames[name] = true
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		if ident, isIdent := node.(*ast.Ident); isIdent {
+			fileImports.taken[ident.Name] = true
+		}
+		return true
+	})
+	for _, name := range pkg.Types.Scope().Names() {
+		fileImports.taken[name] = true
+	}
+	return fileImports
+}
+
+// packageName returns the declared name of an imported package.
+func (fi *FileImports) packageName(packagePath string) string {
+	if imported, exists := fi.pkg.Imports[packagePath]; exists && imported.Name != "" {
+		return imported.Name
+	}
+	parts := strings.Split(packagePath, "/")
+	return parts[len(parts)-1]
+}
+
+// IsImportName checks whether name refers to an imported package.
+func (fi *FileImports) IsImportName(name string) bool {
+	return fi.importNames[name]
+}
+
+// Name returns the name used to qualify identifiers from the given package.
+// An empty name means no qualification is needed, as the package is the current package or
+// is dot-imported.
+// If the package is not imported yet, it is imported under a name that does not collide with
+// any other name in the file.
+func (fi *FileImports) Name(pkg *types.Package) string {
+	if pkg == nil || pkg.Path() == fi.pkg.PkgPath {
+		return ""
+	}
+	if name, exists := fi.names[pkg.Path()]; exists {
+		if name == "." {
+			return ""
+		}
+		return name
+	}
+
+	namer := Namer{name: pkg.Name()}
+	for fi.taken[namer.Name()] || fi.importNames[namer.Name()] {
+		namer.Next()
+	}
+	name := namer.Name()
+
+	importLine := ImportLine{Path: strconv.Quote(pkg.Path())}
+	if name != pkg.Name() {
+		importLine.Name = &name
+	}
+	fi.imports = append(fi.imports, importLine)
+	fi.names[pkg.Path()] = name
+	fi.importNames[name] = true
+	return name
+}
+
+// Qualify returns the name of a package-level object of the given package, as it
+// should be written in the generated file.
+func (fi *FileImports) Qualify(pkg *types.Package, name string) string {
+	if packageName := fi.Name(pkg); packageName != "" {
+		return packageName + "." + name
+	}
+	return name
+}
+
+// Used returns the imports used by the generated source.
+// The source is expected not to contain any imports.
+func (fi *FileImports) Used(src []byte) Imports {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		// Invalid code was generated. Keep all the imports, as we can't tell
+		// which are in use, and let the compiler report the actual error.
+		return fi.imports
+	}
+
+	// Identifiers that are not resolved within the file refer to imports, other files,
+	// or the universe scope.
+	qualifiers := make(map[string]bool)
+	unresolved := make(map[string]bool)
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			if ident, isIdent := node.X.(*ast.Ident); isIdent && ident.Obj == nil {
+				qualifiers[ident.Name] = true
+			}
+			ast.Inspect(node.X, func(node ast.Node) bool {
+				if ident, isIdent := node.(*ast.Ident); isIdent && ident.Obj == nil {
+					unresolved[ident.Name] = true
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			if node.Obj == nil {
+				unresolved[node.Name] = true
+			}
+		}
+		return true
+	})
+
+	var used Imports
+	for _, importLine := range fi.imports {
+		packagePath, _ := strconv.Unquote(importLine.Path)
+		switch {
+		case importLine.Name != nil && *importLine.Name == "_", packagePath == "C":
+			used = append(used, importLine)
+		case importLine.Name != nil && *importLine.Name == ".":
+			if fi.usesDotImport(packagePath, unresolved) {
+				used = append(used, importLine)
+			}
+		case qualifiers[fi.names[packagePath]]:
+			used = append(used, importLine)
+		}
+	}
+	return used
+}
+
+// usesDotImport checks whether any of the unresolved names is exported by a dot-imported package.
+func (fi *FileImports) usesDotImport(packagePath string, unresolved map[string]bool) bool {
+	imported, exists := fi.pkg.Imports[packagePath]
+	if !exists || imported.Types == nil {
+		return true
+	}
+	for name := range unresolved {
+		if imported.Types.Scope().Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
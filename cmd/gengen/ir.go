@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// Instruction is a single instruction of a lowered generator body.
+//
+// Lowering a generator produces a flat sequence of instructions, which is simplified before
+// being rendered into the body of the advance function.
+type Instruction interface {
+	// leading returns the comments and line directives rendered before the instruction.
+	leading() *string
+	render() string
+}
+
+// Leading holds the comments and line directives rendered before an instruction.
+type Leading struct {
+	Leading string
+}
+
+func (l *Leading) leading() *string {
+	return &l.Leading
+}
+
+// Code is straight-line code, which continues to the next instruction.
+type Code struct {
+	Leading
+	Text string
+}
+
+func (c *Code) render() string {
+	return strings.TrimLeft(c.Text, " \t\n")
+}
+
+// Label is a jump target.
+type Label struct {
+	Leading
+	Name string
+}
+
+func (l *Label) render() string {
+	return l.Name + ":"
+}
+
+// Goto unconditionally jumps to a label.
+type Goto struct {
+	Leading
+	Label string
+}
+
+func (g *Goto) render() string {
+	return "goto " + g.Label
+}
+
+// Branch jumps to the Then label if the condition holds, and to the Else label otherwise.
+// An empty label continues to the next instruction instead of jumping.
+type Branch struct {
+	Leading
+	Cond string
+	Then string
+	Else string
+}
+
+func (b *Branch) render() string {
+	switch {
+	case b.Then != "" && b.Else != "":
+		return fmt.Sprintf("if %s {\ngoto %s\n} else {\ngoto %s\n}", b.Cond, b.Then, b.Else)
+	case b.Then != "":
+		return fmt.Sprintf("if %s {\ngoto %s\n}", b.Cond, b.Then)
+	case b.Else != "":
+		return fmt.Sprintf("if %s {\ngoto %s\n}", negate(b.Cond), b.Else)
+	default:
+		return fmt.Sprintf("if %s {\n}", b.Cond)
+	}
+}
+
+// negate returns the negation of a condition.
+func negate(cond string) string {
+	expr, err := parser.ParseExpr(cond)
+	if err != nil {
+		return "!(" + cond + ")"
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident, *ast.CallExpr, *ast.SelectorExpr, *ast.IndexExpr, *ast.ParenExpr:
+		return "!" + cond
+	case *ast.UnaryExpr:
+		if expr.Op == token.NOT {
+			return cond[1:]
+		}
+	}
+	return "!(" + cond + ")"
+}
+
+// Return ends the current call to the advance function.
+// The code may contain other statements before the final return statement.
+type Return struct {
+	Leading
+	Text string
+}
+
+func (r *Return) render() string {
+	return strings.TrimLeft(r.Text, " \t\n")
+}
+
+// Instructions is a sequence of instructions, executed in order unless jumped out of.
+type Instructions []Instruction
+
+func (instrs Instructions) String() string {
+	var out strings.Builder
+	for _, instr := range instrs {
+		out.WriteString(*instr.leading())
+		out.WriteString("\n")
+		out.WriteString(instr.render())
+	}
+	return out.String()
+}
+
+// isNop checks whether an instruction does nothing, and only carries its leading comments.
+func isNop(instr Instruction) bool {
+	code, isCode := instr.(*Code)
+	return isCode && strings.TrimSpace(code.Text) == ""
+}
+
+// isTerminator checks whether execution never continues from an instruction to the next one.
+func isTerminator(instr Instruction) bool {
+	switch instr := instr.(type) {
+	case *Goto, *Return:
+		return true
+	case *Branch:
+		return instr.Then != "" && instr.Else != ""
+	}
+	return false
+}
+
+// nextLabels returns the labels that directly follow the instruction at index i, such that
+// jumping to them is the same as continuing to the next instruction.
+func (instrs Instructions) nextLabels(i int) map[string]bool {
+	labels := make(map[string]bool)
+	for _, instr := range instrs[i+1:] {
+		if label, isLabel := instr.(*Label); isLabel {
+			labels[label.Name] = true
+		} else if !isNop(instr) {
+			break
+		}
+	}
+	return labels
+}
+
+// carryLeading keeps the comments of a removed instruction, by moving them to the instruction
+// following it.
+func (instrs *Instructions) carryLeading(removed Instruction, rest Instructions) {
+	if *removed.leading() == "" {
+		return
+	}
+	if len(rest) == 0 {
+		*instrs = append(*instrs, &Code{Leading: Leading{*removed.leading()}})
+		return
+	}
+	*rest[0].leading() = *removed.leading() + *rest[0].leading()
+}
+
+// Simplify removes redundant jumps, labels and unreachable code.
+// The entries are the labels jumped to from outside the instructions, which are always kept.
+func (instrs Instructions) Simplify(entries map[string]bool) Instructions {
+	for changed := true; changed; {
+		changed = false
+		for _, pass := range []func(Instructions, map[string]bool) (Instructions, bool){
+			Instructions.threadJumps,
+			Instructions.removeJumpsToNext,
+			Instructions.removeUnreachable,
+			Instructions.removeUnusedLabels,
+		} {
+			var passChanged bool
+			instrs, passChanged = pass(instrs, entries)
+			changed = changed || passChanged
+		}
+	}
+	return instrs
+}
+
+// threadJumps retargets jumps to labels that are immediately followed by another jump,
+// so that they jump directly to the final target.
+func (instrs Instructions) threadJumps(map[string]bool) (Instructions, bool) {
+	forward := make(map[string]string)
+	for i, instr := range instrs {
+		label, isLabel := instr.(*Label)
+		if !isLabel {
+			continue
+		}
+		for _, next := range instrs[i+1:] {
+			if _, isLabel := next.(*Label); isLabel || isNop(next) {
+				continue
+			}
+			if jump, isGoto := next.(*Goto); isGoto && jump.Label != label.Name {
+				forward[label.Name] = jump.Label
+			}
+			break
+		}
+	}
+	resolve := func(name string) string {
+		seen := make(map[string]bool)
+		for {
+			target, exists := forward[name]
+			if !exists || seen[name] {
+				return name
+			}
+			seen[name] = true
+			name = target
+		}
+	}
+
+	changed := false
+	retarget := func(label *string) {
+		if *label == "" {
+			return
+		}
+		if target := resolve(*label); target != *label {
+			*label = target
+			changed = true
+		}
+	}
+	for _, instr := range instrs {
+		switch instr := instr.(type) {
+		case *Goto:
+			retarget(&instr.Label)
+		case *Branch:
+			retarget(&instr.Then)
+			retarget(&instr.Else)
+		}
+	}
+	return instrs, changed
+}
+
+// removeJumpsToNext removes jumps to the labels directly following them.
+func (instrs Instructions) removeJumpsToNext(map[string]bool) (Instructions, bool) {
+	var result Instructions
+	changed := false
+	for i, instr := range instrs {
+		switch instr := instr.(type) {
+		case *Goto:
+			if instrs.nextLabels(i)[instr.Label] {
+				result.carryLeading(instr, instrs[i+1:])
+				changed = true
+				continue
+			}
+		case *Branch:
+			next := instrs.nextLabels(i)
+			if next[instr.Then] {
+				instr.Then = ""
+				changed = true
+			}
+			if next[instr.Else] {
+				instr.Else = ""
+				changed = true
+			}
+		}
+		result = append(result, instr)
+	}
+	return result, changed
+}
+
+// removeUnreachable removes the instructions that can only be reached by falling through from
+// a terminator.
+func (instrs Instructions) removeUnreachable(map[string]bool) (Instructions, bool) {
+	var result Instructions
+	reachable := true
+	for _, instr := range instrs {
+		if _, isLabel := instr.(*Label); isLabel {
+			reachable = true
+		}
+		if !reachable {
+			continue
+		}
+		result = append(result, instr)
+		if isTerminator(instr) {
+			reachable = false
+		}
+	}
+	return result, len(result) != len(instrs)
+}
+
+// removeUnusedLabels removes the labels that are never jumped to.
+func (instrs Instructions) removeUnusedLabels(entries map[string]bool) (Instructions, bool) {
+	used := make(map[string]bool)
+	for name := range entries {
+		used[name] = true
+	}
+	for _, instr := range instrs {
+		switch instr := instr.(type) {
+		case *Goto:
+			used[instr.Label] = true
+		case *Branch:
+			used[instr.Then] = true
+			used[instr.Else] = true
+		}
+	}
+
+	var result Instructions
+	for i, instr := range instrs {
+		if label, isLabel := instr.(*Label); isLabel && !used[label.Name] {
+			result.carryLeading(instr, instrs[i+1:])
+			continue
+		}
+		result = append(result, instr)
+	}
+	return result, len(result) != len(instrs)
+}
+
+// CheckJumps checks that the entries and all the jumps target labels that are defined exactly once.
+// Go also forbids jumping over variable declarations and into blocks. The lowered code never does
+// either, as its labels are all at the top level, and its variables are all declared before them.
+func (instrs Instructions) CheckJumps(entries map[string]bool) error {
+	defined := make(map[string]bool)
+	for _, instr := range instrs {
+		if label, isLabel := instr.(*Label); isLabel {
+			if defined[label.Name] {
+				return fmt.Errorf("label %s is defined more than once", label.Name)
+			}
+			defined[label.Name] = true
+		}
+	}
+	var targets []string
+	for name := range entries {
+		targets = append(targets, name)
+	}
+	for _, instr := range instrs {
+		switch instr := instr.(type) {
+		case *Goto:
+			targets = append(targets, instr.Label)
+		case *Branch:
+			targets = append(targets, instr.Then, instr.Else)
+		}
+	}
+	for _, target := range targets {
+		if target != "" && !defined[target] {
+			return fmt.Errorf("label %s is not defined", target)
+		}
+	}
+	return nil
+}
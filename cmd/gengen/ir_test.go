@@ -0,0 +1,164 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name   string
+		instrs Instructions
+		want   Instructions
+	}{
+		{
+			name: "JumpToNext",
+			instrs: Instructions{
+				&Code{Text: "a()"},
+				&Goto{Label: "__After1"},
+				&Label{Name: "__After1"},
+				&Code{Text: "b()"},
+			},
+			want: Instructions{
+				&Code{Text: "a()"},
+				&Code{Text: "b()"},
+			},
+		},
+		{
+			name: "EmptyElse",
+			instrs: Instructions{
+				&Branch{Cond: "x", Then: "__Then1", Else: "__Else1"},
+				&Label{Name: "__Then1"},
+				&Code{Text: "a()"},
+				&Goto{Label: "__After1"},
+				&Label{Name: "__Else1"},
+				&Goto{Label: "__After1"},
+				&Label{Name: "__After1"},
+				&Return{Text: "return false"},
+			},
+			want: Instructions{
+				&Branch{Cond: "x", Else: "__After1"},
+				&Code{Text: "a()"},
+				&Label{Name: "__After1"},
+				&Return{Text: "return false"},
+			},
+		},
+		{
+			name: "ThreadJumps",
+			instrs: Instructions{
+				&Branch{Cond: "x", Then: "__Then1", Else: "__Else1"},
+				&Label{Name: "__Then1"},
+				&Return{Text: "return true"},
+				&Label{Name: "__Else1"},
+				&Goto{Label: "__Head2"},
+				&Label{Name: "__Next1"},
+				&Code{Text: "a()"},
+				&Label{Name: "__Head2"},
+				&Return{Text: "return false"},
+			},
+			want: Instructions{
+				&Branch{Cond: "x", Else: "__Head2"},
+				&Return{Text: "return true"},
+				&Label{Name: "__Next1"},
+				&Code{Text: "a()"},
+				&Label{Name: "__Head2"},
+				&Return{Text: "return false"},
+			},
+		},
+		{
+			name: "Unreachable",
+			instrs: Instructions{
+				&Return{Text: "return false"},
+				&Code{Text: "a()"},
+				&Label{Name: "__After1"},
+				&Code{Text: "b()"},
+			},
+			want: Instructions{
+				&Return{Text: "return false"},
+			},
+		},
+		{
+			name: "KeepComments",
+			instrs: Instructions{
+				&Goto{Leading: Leading{"\n\t// done"}, Label: "__After1"},
+				&Label{Name: "__After1"},
+				&Code{Text: "a()"},
+			},
+			want: Instructions{
+				&Code{Leading: Leading{"\n\t// done"}, Text: "a()"},
+			},
+		},
+	}
+	entries := map[string]bool{"__Next1": true}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.instrs.Simplify(entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBranchNegation(t *testing.T) {
+	tests := []struct {
+		cond string
+		want string
+	}{
+		{"adapter.Next()", "if !adapter.Next() {\ngoto __After1\n}"},
+		{"i < n", "if !(i < n) {\ngoto __After1\n}"},
+		{"!done", "if done {\ngoto __After1\n}"},
+	}
+	for _, tt := range tests {
+		branch := &Branch{Cond: tt.cond, Else: "__After1"}
+		if got := branch.render(); got != tt.want {
+			t.Errorf("render() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCheckJumps(t *testing.T) {
+	entries := map[string]bool{"__Next0": true}
+	tests := []struct {
+		name   string
+		instrs Instructions
+		valid  bool
+	}{
+		{
+			name: "Valid",
+			instrs: Instructions{
+				&Label{Name: "__Next0"},
+				&Branch{Cond: "x", Then: "__Label_done"},
+				&Goto{Label: "__Next0"},
+				&Label{Name: "__Label_done"},
+			},
+			valid: true,
+		},
+		{
+			name: "UndefinedLabel",
+			instrs: Instructions{
+				&Label{Name: "__Next0"},
+				&Goto{Label: "__Label_done"},
+			},
+		},
+		{
+			name: "UndefinedEntry",
+			instrs: Instructions{
+				&Code{Text: "x++"},
+			},
+		},
+		{
+			name: "DuplicateLabel",
+			instrs: Instructions{
+				&Label{Name: "__Next0"},
+				&Label{Name: "__Next0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.instrs.CheckJumps(entries); (err == nil) != tt.valid {
+				t.Errorf("CheckJumps() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/cfg"
+)
+
+// findLiveAcrossYields finds the variables that are live after a yield.
+//
+// The generated advance function returns on every yield, and resumes from it on the next call.
+// Only the values of variables that may be read after a yield need to survive between calls, so
+// only those variables are hoisted out of the advance function.
+func findLiveAcrossYields(info *types.Info, body *ast.BlockStmt) map[types.Object]bool {
+	// Identifiers that are assigned to, rather than read from.
+	assigned := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.AssignStmt:
+			if node.Tok == token.ASSIGN || node.Tok == token.DEFINE {
+				for _, lhs := range node.Lhs {
+					if ident, isIdent := lhs.(*ast.Ident); isIdent {
+						assigned[ident] = true
+					}
+				}
+			}
+		case *ast.RangeStmt:
+			for _, expr := range []ast.Expr{node.Key, node.Value} {
+				if ident, isIdent := expr.(*ast.Ident); isIdent {
+					assigned[ident] = true
+				}
+			}
+		}
+		return true
+	})
+
+	// transfer updates the live set from after the node to before it.
+	transfer := func(node ast.Node, live map[types.Object]bool) {
+		var uses []types.Object
+		ast.Inspect(node, func(node ast.Node) bool {
+			ident, isIdent := node.(*ast.Ident)
+			if !isIdent {
+				return true
+			}
+			if obj, isDef := info.Defs[ident]; isDef && obj != nil {
+				delete(live, obj)
+			} else if obj, isVar := info.Uses[ident].(*types.Var); isVar && isLocalVariable(obj, body) {
+				if assigned[ident] {
+					delete(live, obj)
+				} else {
+					uses = append(uses, obj)
+				}
+			}
+			return true
+		})
+		// Uses are evaluated before the assignments, so they are live before the node.
+		for _, obj := range uses {
+			live[obj] = true
+		}
+	}
+
+	graph := cfg.New(body, func(call *ast.CallExpr) bool { return true })
+
+	// The graph assigns the key and value of range loops before entering the loop, so we move
+	// them to the start of the loop body, where they are assigned on every iteration.
+	nodes := make([][]ast.Node, len(graph.Blocks))
+	rangeVars := make(map[ast.Node]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		if rangeStmt, isRange := node.(*ast.RangeStmt); isRange {
+			for _, expr := range []ast.Expr{rangeStmt.Key, rangeStmt.Value} {
+				if expr != nil {
+					rangeVars[expr] = true
+				}
+			}
+		}
+		_, isFuncLit := node.(*ast.FuncLit)
+		return !isFuncLit
+	})
+	for _, block := range graph.Blocks {
+		for _, node := range block.Nodes {
+			if !rangeVars[node] {
+				nodes[block.Index] = append(nodes[block.Index], node)
+				continue
+			}
+			// The block jumps to the loop head, whose first successor is the loop body.
+			loopBody := block.Succs[0].Succs[0]
+			nodes[loopBody.Index] = append([]ast.Node{node}, nodes[loopBody.Index]...)
+		}
+	}
+
+	liveIn := make([]map[types.Object]bool, len(graph.Blocks))
+	liveOut := func(block *cfg.Block) map[types.Object]bool {
+		live := make(map[types.Object]bool)
+		for _, succ := range block.Succs {
+			for obj := range liveIn[succ.Index] {
+				live[obj] = true
+			}
+		}
+		return live
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(graph.Blocks) - 1; i >= 0; i-- {
+			block := graph.Blocks[i]
+			live := liveOut(block)
+			for j := len(nodes[i]) - 1; j >= 0; j-- {
+				transfer(nodes[i][j], live)
+			}
+			if len(live) != len(liveIn[i]) {
+				liveIn[i] = live
+				changed = true
+			}
+		}
+	}
+
+	liveAcrossYields := make(map[types.Object]bool)
+	for _, block := range graph.Blocks {
+		live := liveOut(block)
+		for j := len(nodes[block.Index]) - 1; j >= 0; j-- {
+			node := nodes[block.Index][j]
+			if isYieldStmt(info, node) {
+				for obj := range live {
+					liveAcrossYields[obj] = true
+				}
+			}
+			transfer(node, live)
+		}
+	}
+	return liveAcrossYields
+}
+
+// isYieldStmt checks whether a node is a statement calling gengen.Yield.
+func isYieldStmt(info *types.Info, node ast.Node) bool {
+	exprStmt, isExprStmt := node.(*ast.ExprStmt)
+	if !isExprStmt {
+		return false
+	}
+	call, isCall := exprStmt.X.(*ast.CallExpr)
+	return isCall && isYieldCall(info, call)
+}
+
+// isYieldCall checks whether a call is a call to gengen.Yield, which may also be dot-imported.
+func isYieldCall(info *types.Info, call *ast.CallExpr) bool {
+	ident := calleeIdent(call.Fun)
+	return ident != nil && isYieldFunc(info.Uses[ident])
+}
+
+// calleeIdent returns the identifier naming the called function, if it can name a gengen function.
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	if index, isIndex := fun.(*ast.IndexExpr); isIndex {
+		// Explicit instantiation, as in gengen.Yield[int](1)
+		fun = index.X
+	}
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	}
+	return nil
+}
+
+// isYieldFunc checks whether an object is the gengen.Yield function.
+func isYieldFunc(obj types.Object) bool {
+	funcObject, isFunc := obj.(*types.Func)
+	return isFunc && funcObject.FullName() == YieldType.String()
+}
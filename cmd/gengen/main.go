@@ -3,14 +3,19 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"flag"
 	"fmt"
 	"github.com/tmr232/gengen"
 	"go/ast"
 	"go/format"
+	"go/printer"
+	"go/token"
 	"go/types"
 	"golang.org/x/tools/go/packages"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 )
@@ -115,6 +120,25 @@ func (imports Imports) String() string {
 	return out.String()
 }
 
+// generatedPath returns the path of the file generated from a generator source file.
+func generatedPath(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, ".go") + "_gengen.go"
+}
+
+// numberLineDirectives sets the line numbers of the `//line` directives mapping code back to the
+// generated file, which are only known once it is formatted.
+func numberLineDirectives(src []byte, filename string) []byte {
+	prefix := "//line " + filename + ":"
+	lines := bytes.Split(src, []byte("\n"))
+	for i, line := range lines {
+		if bytes.HasPrefix(line, []byte(prefix)) {
+			// The directive applies to the line following it, which is line i+2.
+			lines[i] = []byte(fmt.Sprintf("%s%d", prefix, i+2))
+		}
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
 func isGeneratorSourceFile(file *ast.File) bool {
 	if len(file.Comments) == 0 || len(file.Comments[0].List) == 0 {
 		return false
@@ -135,13 +159,65 @@ func getFileImports(file *ast.File) Imports {
 	return imports
 }
 
+// writeDoc writes the doc comment of a node, preceded by line directives mapping it back to the source.
+//
+// gofmt moves directives that are a part of a doc comment to its end, so the directive is separated
+// from the doc comment by an empty line.
+// Before Go 1.22, go/parser groups comments based on their directive-adjusted lines, and may still
+// group the directive with the doc comment. To avoid that, we first reset the line to 1.
+func writeDoc(out *bytes.Buffer, pkgWiz *PkgWizard, node ast.Node, doc *ast.CommentGroup) {
+	if doc == nil {
+		out.WriteString(pkgWiz.LineDirective(node) + "\n")
+		return
+	}
+	position := pkgWiz.pkg.Fset.Position(doc.Pos())
+	filename := filepath.Base(position.Filename)
+	fmt.Fprintf(out, "\n//line %s:1\n//line %s:%d\n\n", filename, filename, position.Line-1)
+	for _, comment := range doc.List {
+		out.WriteString(comment.Text + "\n")
+	}
+}
+
+// writeCommentedNode writes a node as-is, along with its doc comment and all the comments within it.
+func writeCommentedNode(out *bytes.Buffer, pkgWiz *PkgWizard, file *ast.File, node ast.Node) {
+	var comments []*ast.CommentGroup
+	for _, comment := range file.Comments {
+		if comment.Pos() >= node.Pos() && comment.End() <= node.End() {
+			comments = append(comments, comment)
+		}
+	}
+
+	// The doc comment is written separately, so we print a copy of the node without it.
+	switch decl := node.(type) {
+	case *ast.FuncDecl:
+		writeDoc(out, pkgWiz, node, decl.Doc)
+		undocumented := *decl
+		undocumented.Doc = nil
+		node = &undocumented
+	case *ast.GenDecl:
+		writeDoc(out, pkgWiz, node, decl.Doc)
+		undocumented := *decl
+		undocumented.Doc = nil
+		node = &undocumented
+	default:
+		writeDoc(out, pkgWiz, node, nil)
+	}
+
+	format.Node(out, pkgWiz.pkg.Fset, &printer.CommentedNode{Node: node, Comments: comments})
+	out.WriteString("\n")
+}
+
 func renderGeneratorFile(wiz *Wizard, pkg *packages.Package, file *ast.File) []byte {
-	pkgWiz := wiz.WithPackage(pkg, getFileImports(file))
+	pkgWiz := wiz.WithPackage(pkg, file)
 
+	header := bytes.Buffer{}
 	out := bytes.Buffer{}
 
 	ast.Inspect(file, func(node ast.Node) bool {
 		switch node := node.(type) {
+		case nil:
+			// ast.Inspect calls us with nil after visiting the children of a node.
+			return false
 		case *ast.File:
 			return true
 		case *ast.Ident:
@@ -151,26 +227,84 @@ func renderGeneratorFile(wiz *Wizard, pkg *packages.Package, file *ast.File) []b
 			if err != nil {
 				log.Fatal("Failed to render package header.")
 			}
-			out.Write(res)
+			header.Write(res)
 			return false
 		case *ast.FuncDecl:
 			if IsGenerator(pkg, node) {
-				out.Write(pkgWiz.WithFunction(node).convertFunction())
+				writeDoc(&out, pkgWiz, node, node.Doc)
+				out.Write(bytes.TrimLeft(pkgWiz.WithFunction(node).convertFunction(), " \t\n"))
 				out.WriteString("\n")
 			} else {
-				format.Node(&out, pkg.Fset, node)
-				out.WriteString("\n")
+				writeCommentedNode(&out, pkgWiz, file, node)
+			}
+			return false
+		case *ast.GenDecl:
+			if node.Tok == token.IMPORT {
+				// Imports are added after rendering, based on what the generated code uses.
+				return false
 			}
+			writeCommentedNode(&out, pkgWiz, file, node)
 			return false
 		default:
-			format.Node(&out, pkg.Fset, node)
-			out.WriteString("\n")
+			writeCommentedNode(&out, pkgWiz, file, node)
 			return false
 		}
 	})
 
-	src := formatSource(out.Bytes())
-	return src
+	body := append(header.Bytes(), out.Bytes()...)
+	imports := pkgWiz.imports.Used(body)
+
+	src := bytes.Buffer{}
+	src.Write(header.Bytes())
+	if len(imports) > 0 {
+		src.WriteString(imports.String())
+	}
+	src.Write(out.Bytes())
+	return numberLineDirectives(formatSource(src.Bytes()), pkgWiz.filename)
+}
+
+// renderGeneratorFunction renders only the lowering of the named generator function(s) in the file.
+// Methods can be selected either by their name or by `Type.Method`.
+func renderGeneratorFunction(wiz *Wizard, pkg *packages.Package, file *ast.File, name string) []byte {
+	pkgWiz := wiz.WithPackage(pkg, file)
+
+	out := bytes.Buffer{}
+	for _, decl := range file.Decls {
+		fdecl, isFunc := decl.(*ast.FuncDecl)
+		if !isFunc || !matchesFunctionName(fdecl, name) || !IsGenerator(pkg, fdecl) {
+			continue
+		}
+		// Strip the template's leading indentation, as format.Source keeps
+		// the indentation of the first line for partial sources.
+		out.Write(bytes.TrimSpace(pkgWiz.WithFunction(fdecl).convertFunction()))
+		out.WriteString("\n\n")
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+
+	return numberLineDirectives(formatSource(out.Bytes()), pkgWiz.filename)
+}
+
+func matchesFunctionName(fdecl *ast.FuncDecl, name string) bool {
+	if fdecl.Name.Name == name {
+		return true
+	}
+	if fdecl.Recv == nil || len(fdecl.Recv.List) != 1 {
+		return false
+	}
+	recvType := fdecl.Recv.List[0].Type
+	if star, isStar := recvType.(*ast.StarExpr); isStar {
+		recvType = star.X
+	}
+	switch typ := recvType.(type) {
+	case *ast.IndexExpr:
+		recvType = typ.X
+	case *ast.IndexListExpr:
+		recvType = typ.X
+	}
+	recvName, isIdent := recvType.(*ast.Ident)
+	return isIdent && recvName.Name+"."+fdecl.Name.Name == name
 }
 
 func loadPackages(dir string, tags ...string) ([]*packages.Package, error) {
@@ -195,19 +329,52 @@ func loadPackages(dir string, tags ...string) ([]*packages.Package, error) {
 }
 
 func main() {
+	var dryRun bool
+	var funcName string
+	var structs bool
+	var snapshots bool
+	var clones bool
+	flag.BoolVar(&dryRun, "n", false, "print the rendered output to stdout instead of writing `_gengen.go` files")
+	flag.BoolVar(&dryRun, "stdout", false, "same as -n")
+	flag.StringVar(&funcName, "func", "", "only render the lowering of the named generator function (implies -n)")
+	flag.BoolVar(&structs, "struct", false, "generate struct-based state machines instead of closures, for all generators")
+	flag.BoolVar(&snapshots, "snapshot", false, "generate state machines that can be snapshotted and restored, for all generators (implies -struct)")
+	flag.BoolVar(&clones, "clone", false, "generate state machines that can be cloned, for all generators (implies -struct)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: gengen [flags] [generator source files]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
 	dir := "."
 	buildTag := "gengen"
 
+	// When source files are given, only they are rendered.
+	// Otherwise, all the generator source files in the package are.
+	onlyFiles := make(map[string]bool)
+	for _, arg := range flag.Args() {
+		path, err := filepath.Abs(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		onlyFiles[path] = true
+	}
+
 	pkgs, err := loadPackages(dir, buildTag)
 
 	wiz := NewWizard()
 	if wiz == nil {
 		log.Fatal("Failed to initialize wizard.")
 	}
+	wiz.structs = structs
+	wiz.snapshots = snapshots
+	wiz.clones = clones
 
 	log.Println("Generating Generators!")
 
 	visited := make(map[*ast.File]bool)
+	rendered := make(map[string]bool)
+	foundFunc := false
 
 	for _, pkg := range pkgs {
 		for _, file := range pkg.Syntax {
@@ -222,16 +389,44 @@ func main() {
 			}
 
 			sourcePath := pkg.Fset.Position(file.Pos()).Filename
-			genPath := strings.TrimSuffix(sourcePath, ".go") + "_gengen.go"
+			if len(onlyFiles) > 0 && !onlyFiles[sourcePath] {
+				continue
+			}
+			rendered[sourcePath] = true
+
+			if funcName != "" {
+				src := renderGeneratorFunction(wiz, pkg, file, funcName)
+				if src != nil {
+					foundFunc = true
+					os.Stdout.Write(src)
+				}
+				continue
+			}
+
+			genPath := generatedPath(sourcePath)
 
 			log.Printf("\t%s -> %s\n", sourcePath, genPath)
 
 			src := renderGeneratorFile(wiz, pkg, file)
 
+			if dryRun {
+				os.Stdout.Write(src)
+				continue
+			}
+
 			err = ioutil.WriteFile(genPath, src, 0644)
 			if err != nil {
 				log.Fatalf("writing output: %s", err)
 			}
 		}
 	}
+
+	for path := range onlyFiles {
+		if !rendered[path] {
+			log.Fatalf("%s is not a generator source file", path)
+		}
+	}
+	if funcName != "" && !foundFunc {
+		log.Fatalf("no generator function named %s", funcName)
+	}
 }
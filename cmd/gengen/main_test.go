@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderTestdata renders the generator source file of a testdata package.
+func renderTestdata(t *testing.T, wiz *Wizard, name string) string {
+	pkgs, err := loadPackages("testdata/"+name, "gengen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if isGeneratorSourceFile(file) {
+				return string(renderGeneratorFile(wiz, pkg, file))
+			}
+		}
+	}
+	t.Fatalf("No generator source file in testdata/%s", name)
+	return ""
+}
+
+// testdataDiagnostics runs a check on every generator function of the generator source file of a
+// testdata package, and returns its diagnostics.
+// Positions are reduced to the file name and line, which are stable.
+func testdataDiagnostics(t *testing.T, name string, check func(wiz *FuncWizard) []string) []string {
+	pkgs, err := loadPackages("testdata/"+name, "gengen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var diagnostics []string
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if !isGeneratorSourceFile(file) {
+				continue
+			}
+			pkgWiz := NewWizard().WithPackage(pkg, file)
+			for _, decl := range file.Decls {
+				if fdecl, isFunc := decl.(*ast.FuncDecl); isFunc && IsGenerator(pkg, fdecl) {
+					wiz := pkgWiz.WithFunction(fdecl)
+					wiz.analyzeVariables()
+					diagnostics = append(diagnostics, check(wiz)...)
+				}
+			}
+			for i, diagnostic := range diagnostics {
+				position, message, _ := strings.Cut(diagnostic, ": ")
+				position = filepath.Base(position)
+				diagnostics[i] = position[:strings.LastIndex(position, ":")] + ": " + message
+			}
+			return diagnostics
+		}
+	}
+	t.Fatalf("No generator source file in testdata/%s", name)
+	return nil
+}
+
+func TestRenderKeepsComments(t *testing.T) {
+	src := renderTestdata(t, NewWizard(), "comments")
+
+	file, err := parser.ParseFile(token.NewFileSet(), "comments_gengen.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := make(map[string]string)
+	for _, decl := range file.Decls {
+		if fdecl, isFunc := decl.(*ast.FuncDecl); isFunc {
+			docs[fdecl.Name.Name] = fdecl.Doc.Text()
+		}
+	}
+	wantDocs := map[string]string{
+		"Countdown": "Countdown yields the numbers from n down to 1.\n",
+		"double":    "double is a regular function.\n",
+	}
+	for name, want := range wantDocs {
+		if docs[name] != want {
+			t.Errorf("Doc of %s = %q, want %q", name, docs[name], want)
+		}
+	}
+
+	for _, comment := range []string{"// Count down, one at a time.", "// yield before decrementing", "// Multiply by two."} {
+		if !strings.Contains(src, comment) {
+			t.Errorf("Missing comment %q", comment)
+		}
+	}
+}
+
+// TestLineDirectives checks the positions the generated code is attributed to, as reported by
+// panics and coverage profiles.
+// Coverage profiles attribute all of a function to the file it starts in, so functions running the
+// lowered statements are mapped to the generator source, and the rest to the generated file.
+func TestLineDirectives(t *testing.T) {
+	src, err := os.ReadFile("testdata/lines/lines.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceLines := bytes.Count(src, []byte("\n"))
+
+	structs := NewWizard()
+	structs.structs, structs.snapshots, structs.clones = true, true, true
+	for mode, wiz := range map[string]*Wizard{"closures": NewWizard(), "structs": structs} {
+		t.Run(mode, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "lines_gengen.go", renderTestdata(t, wiz, "lines"), parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, decl := range file.Decls {
+				wantFile := "lines_gengen.go"
+				if fdecl, isFunc := decl.(*ast.FuncDecl); isFunc && (fdecl.Recv == nil || fdecl.Name.Name == "Next") {
+					wantFile = "lines.go"
+				}
+				ast.Inspect(decl, func(node ast.Node) bool {
+					if node == nil {
+						return false
+					}
+					position := fset.Position(node.Pos())
+					line := fset.PositionFor(node.Pos(), false).Line
+					switch {
+					case position.Filename != wantFile:
+						t.Errorf("%T at line %d attributed to %s, want %s", node, line, position, wantFile)
+					case wantFile == "lines.go" && position.Line > sourceLines:
+						t.Errorf("%T at line %d attributed to %s, past the end of the source", node, line, position)
+					case wantFile == "lines_gengen.go" && position.Line != line:
+						t.Errorf("%T at line %d attributed to %s", node, line, position)
+					}
+					return true
+				})
+			}
+		})
+	}
+}
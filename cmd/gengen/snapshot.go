@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// The directive requesting a generator that can be snapshotted and restored.
+const snapshotDirective = "//gengen:snapshot"
+
+// checkSnapshot returns diagnostics for all the state of a generator that cannot be serialized
+// in its snapshots: its arguments, its hoisted variables, the values it yields, and the adapters
+// of the loops it yields in.
+func (wiz *FuncWizard) checkSnapshot(itemType types.Type) []string {
+	var diagnostics []string
+	report := func(node interface{ Pos() token.Pos }, message string) {
+		diagnostics = append(diagnostics, fmt.Sprintf("%s: %s", wiz.pkg.Fset.Position(node.Pos()), message))
+	}
+	qualifier := wiz.diagnosticQualifier()
+
+	for _, obj := range wiz.stateVariables() {
+		if reason := unserializable(obj.Type(), qualifier, nil); reason != "" {
+			report(obj, fmt.Sprintf("%s cannot be snapshotted: %s", obj.Name(), reason))
+		}
+	}
+	if reason := unserializable(itemType, qualifier, nil); reason != "" {
+		report(wiz.fdecl.Type.Results, fmt.Sprintf("yielded values cannot be snapshotted: %s", reason))
+	}
+
+	for _, node := range wiz.yieldingMapRanges() {
+		report(node, "map iteration order cannot be snapshotted, range over gengen.Sorted(m) instead")
+	}
+	return diagnostics
+}
+
+// stateVariables returns the variables kept in the state machine of the generator, in the order
+// of their declarations: its receiver and arguments, and its hoisted variables.
+func (wiz *FuncWizard) stateVariables() []types.Object {
+	var state []types.Object
+	signature := wiz.pkg.TypesInfo.Defs[wiz.fdecl.Name].Type().(*types.Signature)
+	if signature.Recv() != nil {
+		state = append(state, signature.Recv())
+	}
+	for i := 0; i < signature.Params().Len(); i++ {
+		state = append(state, signature.Params().At(i))
+	}
+	var hoisted []types.Object
+	for obj := range wiz.hoisted {
+		hoisted = append(hoisted, obj)
+	}
+	sort.Slice(hoisted, func(i, j int) bool { return hoisted[i].Pos() < hoisted[j].Pos() })
+	state = append(state, hoisted...)
+
+	// Arguments may be hoisted as well.
+	seen := make(map[types.Object]bool)
+	var unique []types.Object
+	for _, obj := range state {
+		if obj.Name() != "_" && obj.Name() != "" && !seen[obj] {
+			seen[obj] = true
+			unique = append(unique, obj)
+		}
+	}
+	return unique
+}
+
+// diagnosticQualifier qualifies the types in diagnostics by package names, as they are written in code.
+func (wiz *FuncWizard) diagnosticQualifier() types.Qualifier {
+	return func(pkg *types.Package) string {
+		if pkg == wiz.pkg.Types {
+			return ""
+		}
+		return pkg.Name()
+	}
+}
+
+// yieldingMapRanges returns the loops ranging over maps in Go's iteration order that yield,
+// so their adapters are a part of the state of the generator.
+func (wiz *FuncWizard) yieldingMapRanges() []*ast.RangeStmt {
+	var ranges []*ast.RangeStmt
+	ast.Inspect(wiz.fdecl.Body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.RangeStmt:
+			_, isMap := coreType(wiz.pkg.TypesInfo.TypeOf(node.X)).(*types.Map)
+			if _, _, isSorted := wiz.sortedMap(node.X); isMap && !isSorted && usesYield(wiz.pkg, node.Body) {
+				ranges = append(ranges, node)
+			}
+		}
+		return true
+	})
+	return ranges
+}
+
+// unserializable returns the reason values of a type cannot be serialized in a snapshot,
+// or an empty string if they can.
+//
+// Snapshots are serialized using encoding/gob, which fails on channels and functions,
+// and silently drops unexported struct fields.
+// Types implementing gob.GobEncoder or encoding.BinaryMarshaler serialize themselves.
+// The dynamic types of interfaces and type parameters are only checked when serializing.
+func unserializable(typ types.Type, qualifier types.Qualifier, checked []types.Type) string {
+	for _, checkedType := range checked {
+		if types.Identical(typ, checkedType) {
+			// Recursive types are checked once.
+			return ""
+		}
+	}
+	switch typ := typ.(type) {
+	case *types.Named:
+		for _, method := range []string{"GobEncode", "MarshalBinary"} {
+			if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(typ), false, nil, method); obj != nil {
+				return ""
+			}
+		}
+		checked = append(checked, typ)
+		if structType, isStruct := typ.Underlying().(*types.Struct); isStruct {
+			return unserializableFields(structType, types.TypeString(typ, qualifier), qualifier, checked)
+		}
+		return unserializable(typ.Underlying(), qualifier, checked)
+	case *types.Basic:
+		if typ.Kind() == types.UnsafePointer {
+			return "unsafe.Pointer cannot be serialized"
+		}
+	case *types.Chan, *types.Signature:
+		return types.TypeString(typ, qualifier) + " cannot be serialized"
+	case *types.Pointer:
+		return unserializable(typ.Elem(), qualifier, checked)
+	case *types.Slice:
+		return unserializable(typ.Elem(), qualifier, checked)
+	case *types.Array:
+		return unserializable(typ.Elem(), qualifier, checked)
+	case *types.Map:
+		if reason := unserializable(typ.Key(), qualifier, checked); reason != "" {
+			return reason
+		}
+		return unserializable(typ.Elem(), qualifier, checked)
+	case *types.Struct:
+		return unserializableFields(typ, types.TypeString(typ, qualifier), qualifier, checked)
+	}
+	return ""
+}
+
+// unserializableFields returns the reason values of a struct type cannot be serialized in
+// a snapshot, or an empty string if they can.
+func unserializableFields(typ *types.Struct, name string, qualifier types.Qualifier, checked []types.Type) string {
+	for i := 0; i < typ.NumFields(); i++ {
+		field := typ.Field(i)
+		if !field.Exported() {
+			return fmt.Sprintf("%s has unexported field %s", name, field.Name())
+		}
+		if reason := unserializable(field.Type(), qualifier, checked); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+func TestCheckSnapshot(t *testing.T) {
+	diagnostics := testdataDiagnostics(t, "snapshots", func(wiz *FuncWizard) []string {
+		return wiz.checkSnapshot(wiz.pkg.TypesInfo.TypeOf(wiz.fdecl.Type.Results.List[0].Type).(*types.Named).TypeArgs().At(0))
+	})
+
+	want := []string{
+		"snapshots.go:16: done cannot be snapshotted: chan bool cannot be serialized",
+		"snapshots.go:16: counters cannot be snapshotted: counter has unexported field count",
+		"snapshots.go:17: callback cannot be snapshotted: func() int cannot be serialized",
+		"snapshots.go:16: yielded values cannot be snapshotted: func() int cannot be serialized",
+		"snapshots.go:18: map iteration order cannot be snapshotted, range over gengen.Sorted(m) instead",
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("checkSnapshot() = %q, want %q", diagnostics, want)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build gengen
+
+package clones
+
+import "github.com/tmr232/gengen"
+
+type source struct {
+	items gengen.Generator[int]
+}
+
+func Uncloneable(gen gengen.Generator[int], sources []source, m map[string]int) gengen.Generator[int] {
+	for gen.Next() {
+		gengen.Yield(gen.Value())
+	}
+	for _, value := range m {
+		gengen.Yield(value)
+	}
+	return nil
+}
+
+func Cloneable(values []int, m map[string]int, callback func(int) int) gengen.Generator[int] {
+	for _, value := range values {
+		gengen.Yield(callback(value))
+	}
+	for key := range gengen.Sorted(m) {
+		gengen.Yield(m[key])
+	}
+	return nil
+}
+
+var shared gengen.Generator[int]
+
+func PackageGenerator(n int) gengen.Generator[int] {
+	for i := 0; i < n; i++ {
+		gengen.Yield(i)
+		shared.Next()
+	}
+	return nil
+}
+
+type countdown struct {
+	n int
+}
+
+func (c *countdown) Next() bool   { c.n--; return c.n >= 0 }
+func (c *countdown) Value() int   { return c.n }
+func (c *countdown) Error() error { return nil }
+
+func SharedIterators(iterator gengen.Iterator[int], peekable *gengen.Peekable[int], custom *countdown, values countdown) gengen.Generator[int] {
+	for iterator.Next() && peekable.Next() && custom.Next() && values.n > 0 {
+		gengen.Yield(iterator.Value() + peekable.Value() + custom.Value())
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build gengen
+
+package comments
+
+import "github.com/tmr232/gengen"
+
+// Countdown yields the numbers from n down to 1.
+func Countdown(n int) gengen.Generator[int] {
+	// Count down, one at a time.
+	for n > 0 {
+		gengen.Yield(n) // yield before decrementing
+		n--
+	}
+	return nil
+}
+
+// double is a regular function.
+func double(x int) int {
+	// Multiply by two.
+	return x * 2
+}
@@ -0,0 +1,27 @@
+//go:build gengen
+
+package lines
+
+import "github.com/tmr232/gengen"
+
+func Squares(n int) gengen.Generator[int] {
+	for i := 0; i < n; i++ {
+		square := i * i
+		gengen.Yield(square)
+	}
+	return nil
+}
+
+func Signs(values []int) gengen.Generator[string] {
+	for _, value := range values {
+		if value < 0 {
+			gengen.Yield("-")
+		} else {
+			gengen.Yield("+")
+		}
+	}
+	if len(values) == 0 {
+		gengen.Yield("empty")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build gengen
+
+package snapshots
+
+import "github.com/tmr232/gengen"
+
+type counter struct {
+	count int
+}
+
+type Exported struct {
+	Count int
+	Next  *Exported
+}
+
+func Unserializable(done chan bool, counters []counter, m map[string]int) gengen.Generator[func() int] {
+	callback := func() int { return 1 }
+	for key := range m {
+		gengen.Yield(callback)
+		gengen.Yield(func() int { return m[key] })
+	}
+	for key := range gengen.Sorted(m) {
+		gengen.Yield(func() int { return m[key] })
+	}
+	return nil
+}
+
+func Serializable(list *Exported, counts map[string][]int, value any) gengen.Generator[int] {
+	for list != nil {
+		gengen.Yield(list.Count)
+		list = list.Next
+	}
+	for _, count := range counts["a"] {
+		gengen.Yield(count)
+	}
+	return nil
+}
+
+var logf = func(string) {}
+
+func PackageVariables(values []int) gengen.Generator[int] {
+	for _, value := range values {
+		gengen.Yield(value)
+		logf("yielded")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build gengen
+
+package yields
+
+import "github.com/tmr232/gengen"
+
+func Misplaced() gengen.Generator[int] {
+	gengen.Yield(1)
+	go gengen.Yield(2)
+	defer gengen.Yield(3)
+	yield := gengen.Yield
+	yield(4)
+	func() {
+		gengen.Yield(5)
+	}()
+	return nil
+}
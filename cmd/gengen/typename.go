@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+// getTypeName returns the name of a type, as it should be written in the generated file.
+// Packages are qualified by their names in the generated file, and are imported if needed.
+func (wiz *FuncWizard) getTypeName(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Basic:
+		if typ.Kind() == types.UnsafePointer {
+			return wiz.imports.Qualify(types.Unsafe, "Pointer")
+		}
+		return types.Default(typ).String()
+	case *types.Named:
+		if hoisted, isHoisted := wiz.variables[typ.Obj()]; isHoisted {
+			// Local types are hoisted to the package level.
+			return hoisted
+		}
+		return wiz.imports.Qualify(typ.Obj().Pkg(), typ.Obj().Name()) + wiz.getTypeArgs(typ.TypeArgs())
+	case *types.TypeParam:
+		return typ.Obj().Name()
+	case *types.Pointer:
+		return "*" + wiz.getTypeName(typ.Elem())
+	case *types.Slice:
+		return "[]" + wiz.getTypeName(typ.Elem())
+	case *types.Array:
+		return fmt.Sprintf("[%d]%s", typ.Len(), wiz.getTypeName(typ.Elem()))
+	case *types.Map:
+		return fmt.Sprintf("map[%s]%s", wiz.getTypeName(typ.Key()), wiz.getTypeName(typ.Elem()))
+	case *types.Chan:
+		switch typ.Dir() {
+		case types.SendOnly:
+			return "chan<- " + wiz.getTypeName(typ.Elem())
+		case types.RecvOnly:
+			return "<-chan " + wiz.getTypeName(typ.Elem())
+		}
+		if elem, isChan := typ.Elem().(*types.Chan); isChan && elem.Dir() == types.RecvOnly {
+			// Without parentheses, the receive operator applies to the outer channel.
+			return "chan (" + wiz.getTypeName(elem) + ")"
+		}
+		return "chan " + wiz.getTypeName(typ.Elem())
+	case *types.Signature:
+		return "func" + wiz.getSignature(typ)
+	case *types.Struct:
+		fields := make([]string, typ.NumFields())
+		for i := range fields {
+			field := typ.Field(i)
+			fields[i] = wiz.getTypeName(field.Type())
+			if !field.Embedded() {
+				fields[i] = field.Name() + " " + fields[i]
+			}
+			if tag := typ.Tag(i); tag != "" {
+				if strconv.CanBackquote(tag) {
+					fields[i] += " `" + tag + "`"
+				} else {
+					fields[i] += " " + strconv.Quote(tag)
+				}
+			}
+		}
+		return "struct{" + strings.Join(fields, "; ") + "}"
+	case *types.Interface:
+		var elements []string
+		for i := 0; i < typ.NumEmbeddeds(); i++ {
+			elements = append(elements, wiz.getTypeName(typ.EmbeddedType(i)))
+		}
+		for i := 0; i < typ.NumExplicitMethods(); i++ {
+			method := typ.ExplicitMethod(i)
+			elements = append(elements, method.Name()+wiz.getSignature(method.Type().(*types.Signature)))
+		}
+		return "interface{" + strings.Join(elements, "; ") + "}"
+	case *types.Union:
+		terms := make([]string, typ.Len())
+		for i := range terms {
+			terms[i] = wiz.getTypeName(typ.Term(i).Type())
+			if typ.Term(i).Tilde() {
+				terms[i] = "~" + terms[i]
+			}
+		}
+		return strings.Join(terms, " | ")
+	}
+	return typ.String()
+}
+
+// getConstraint returns the constraint of a type parameter, as it should be written in its type
+// parameter list.
+// Constraints written as a bare type set, such as ~[]E, are implicit interfaces, and are written
+// back the same way, unless they are pointers, which are ambiguous in type declarations.
+func (wiz *FuncWizard) getConstraint(typeParam *types.TypeParam) string {
+	constraint, isInterface := typeParam.Constraint().(*types.Interface)
+	if !isInterface {
+		return wiz.getTypeName(typeParam.Constraint())
+	}
+	if constraint.IsImplicit() && constraint.NumEmbeddeds() == 1 {
+		if _, isPointer := constraint.EmbeddedType(0).(*types.Pointer); !isPointer {
+			return wiz.getTypeName(constraint.EmbeddedType(0))
+		}
+	} else if constraint.Empty() && wiz.pkg.Types.Scope().Lookup("any") == nil {
+		return "any"
+	}
+	return wiz.getTypeName(constraint)
+}
+
+// coreType returns the underlying type shared by all the types in the type set of a type,
+// or nil if there is no such type.
+// For types other than type parameters, this is simply the underlying type.
+func coreType(typ types.Type) types.Type {
+	typeParam, isTypeParam := typ.(*types.TypeParam)
+	if !isTypeParam {
+		return typ.Underlying()
+	}
+	var core types.Type
+	for _, term := range typeSetTerms(typeParam.Constraint()) {
+		if core == nil {
+			core = term.Underlying()
+		} else if !types.Identical(core, term.Underlying()) {
+			return nil
+		}
+	}
+	return core
+}
+
+// typeSetTerms returns the types listed in the type set of a constraint, including the ones of
+// the interfaces it embeds, or the type itself if it is not an interface.
+func typeSetTerms(typ types.Type) []types.Type {
+	var terms []types.Type
+	switch typ := typ.(type) {
+	case *types.Union:
+		for i := 0; i < typ.Len(); i++ {
+			terms = append(terms, typeSetTerms(typ.Term(i).Type())...)
+		}
+	default:
+		iface, isInterface := typ.Underlying().(*types.Interface)
+		if !isInterface {
+			return []types.Type{typ}
+		}
+		for i := 0; i < iface.NumEmbeddeds(); i++ {
+			terms = append(terms, typeSetTerms(iface.EmbeddedType(i))...)
+		}
+	}
+	return terms
+}
+
+// getZeroValue returns an expression evaluating to the zero value of a type.
+func (wiz *FuncWizard) getZeroValue(typ types.Type) string {
+	switch underlying := typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case underlying.Info()&types.IsBoolean != 0:
+			return "false"
+		case underlying.Info()&types.IsString != 0:
+			return `""`
+		case underlying.Info()&types.IsNumeric != 0:
+			return "0"
+		}
+		return "nil"
+	case *types.Struct, *types.Array:
+		return wiz.getTypeName(typ) + "{}"
+	case *types.Interface:
+		if _, isTypeParam := typ.(*types.TypeParam); isTypeParam {
+			return "*new(" + wiz.getTypeName(typ) + ")"
+		}
+	}
+	return "nil"
+}
+
+// getTypeArgs returns the type argument list instantiating a generic type.
+func (wiz *FuncWizard) getTypeArgs(typeArgs *types.TypeList) string {
+	if typeArgs.Len() == 0 {
+		return ""
+	}
+	args := make([]string, typeArgs.Len())
+	for i := range args {
+		args[i] = wiz.getTypeName(typeArgs.At(i))
+	}
+	return "[" + strings.Join(args, ", ") + "]"
+}
+
+// getSignature returns the parameters and results of a function type.
+func (wiz *FuncWizard) getSignature(signature *types.Signature) string {
+	params := make([]string, signature.Params().Len())
+	for i := range params {
+		param := signature.Params().At(i)
+		if signature.Variadic() && i == len(params)-1 {
+			params[i] = "..." + wiz.getTypeName(param.Type().(*types.Slice).Elem())
+		} else {
+			params[i] = wiz.getTypeName(param.Type())
+		}
+	}
+	results := make([]string, signature.Results().Len())
+	for i := range results {
+		results[i] = wiz.getTypeName(signature.Results().At(i).Type())
+	}
+
+	switch len(results) {
+	case 0:
+		return "(" + strings.Join(params, ", ") + ")"
+	case 1:
+		return "(" + strings.Join(params, ", ") + ") " + results[0]
+	default:
+		return "(" + strings.Join(params, ", ") + ") (" + strings.Join(results, ", ") + ")"
+	}
+}
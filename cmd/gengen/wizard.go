@@ -10,12 +10,24 @@ import (
 	"go/types"
 	"golang.org/x/tools/go/packages"
 	"log"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"text/template"
 )
 
 type Wizard struct {
 	template *template.Template
+	// Generate struct-based state machines for all generators
+	structs bool
+	// Generate state machines that can be snapshotted and restored for all generators
+	snapshots bool
+	// Generate state machines that can be cloned for all generators
+	clones bool
+	// The package-level names generated so far, by package path.
+	// A package may have several generator source files, all generated into the same scope.
+	generated map[string]map[string]bool
 }
 
 //go:embed gengen.tmpl
@@ -31,36 +43,19 @@ func NewWizard() *Wizard {
 		log.Fatal(err)
 	}
 
-	return &Wizard{template: t}
+	return &Wizard{template: t, generated: make(map[string]map[string]bool)}
 }
 
-// Return a mapping between package paths and imports, and a set of all the import names
-func createImportNameMapping(imports Imports) (map[string]string, map[string]bool) {
-	mapping := make(map[string]string)
-	importNames := make(map[string]bool)
-	for _, importLine := range imports {
-		packagePath := strings.Trim(importLine.Path, "\"")
-		if importLine.Name != nil {
-			name := *importLine.Name
-			mapping[packagePath] = name
-			importNames[name] = true
-		} else {
-			parts := strings.Split(packagePath, "/")
-			name := parts[len(parts)-1]
-			mapping[packagePath] = name
-			importNames[name] = true
-		}
+func (wiz *Wizard) WithPackage(pkg *packages.Package, file *ast.File) *PkgWizard {
+	if wiz.generated[pkg.PkgPath] == nil {
+		wiz.generated[pkg.PkgPath] = make(map[string]bool)
 	}
-	return mapping, importNames
-}
-
-func (wiz *Wizard) WithPackage(pkg *packages.Package, imports Imports) *PkgWizard {
-	importMapping, importNames := createImportNameMapping(imports)
 	return &PkgWizard{
-		Wizard:      *wiz,
-		pkg:         pkg,
-		imports:     importMapping,
-		importNames: importNames,
+		Wizard:   *wiz,
+		pkg:      pkg,
+		imports:  NewFileImports(pkg, file),
+		comments: ast.NewCommentMap(pkg.Fset, file, file.Comments),
+		filename: filepath.Base(generatedPath(pkg.Fset.Position(file.Pos()).Filename)),
 	}
 }
 func (wiz *Wizard) Render(name string, data any) ([]byte, error) {
@@ -76,20 +71,151 @@ func (wiz *Wizard) Render(name string, data any) ([]byte, error) {
 type PkgWizard struct {
 	Wizard
 	pkg *packages.Package
-	// A mapping between package paths to import names
-	imports map[string]string
+	// The imports of the generated file
+	imports *FileImports
+
+	// The comments of the file, by the nodes they are associated with
+	comments ast.CommentMap
+
+	// The name of the generated file
+	filename string
+}
+
+// Comments returns the comments associated with node, in a form that can be placed
+// before the code generated from it.
+// The comments are indented, so that gofmt does not mistake them for top-level doc comments.
+func (wiz *PkgWizard) Comments(node ast.Node) string {
+	var out strings.Builder
+	for _, group := range wiz.comments[node] {
+		for _, comment := range group.List {
+			out.WriteString("\n\t" + comment.Text)
+		}
+	}
+	return out.String()
+}
+
+// LineDirective returns a `//line` directive mapping the generated code that follows it
+// back to the position of node in the generator source.
+// The directive starts with a newline, as it must be at the beginning of a line, and must be followed
+// by a single newline, as it applies to the line after it.
+func (wiz *PkgWizard) LineDirective(node ast.Node) string {
+	position := wiz.pkg.Fset.Position(node.Pos())
+	return fmt.Sprintf("\n//line %s:%d", filepath.Base(position.Filename), position.Line)
+}
+
+// GeneratedLineDirective returns a `//line` directive mapping the code that follows it back to
+// the generated file, for code that has no counterpart in the generator source.
+// The line number is only a placeholder, set by numberLineDirectives once the file is formatted.
+func (wiz *PkgWizard) GeneratedLineDirective() string {
+	return fmt.Sprintf("\n//line %s:1", wiz.filename)
+}
 
-	// A set of all the imported names
-	importNames map[string]bool
+// Gengen returns the name of a gengen package-level object, as it should be written in the generated code.
+func (wiz *PkgWizard) Gengen(name string) string {
+	gengenPackage := types.NewPackage(GeneratorType.PkgPath, "gengen")
+	if imported, exists := wiz.pkg.Imports[GeneratorType.PkgPath]; exists {
+		gengenPackage = imported.Types
+	}
+	return wiz.imports.Qualify(gengenPackage, name)
 }
 
 func (wiz *PkgWizard) WithFunction(fdecl *ast.FuncDecl) *FuncWizard {
-	return &FuncWizard{
+	snapshots := wiz.snapshots || hasDirective(fdecl, snapshotDirective)
+	clones := wiz.clones || hasDirective(fdecl, cloneDirective)
+	funcWiz := &FuncWizard{
 		PkgWizard:   *wiz,
 		fdecl:       fdecl,
+		resumes:     []string{wiz.LineDirective(fdecl)},
 		definitions: make(map[types.Object]string),
 		variables:   make(map[types.Object]string),
 		names:       make(map[string]bool),
+		extraState:  make(map[string]string),
+		extraLocals: make(map[string]string),
+		generic:     make(map[types.Object]bool),
+		// Snapshots and clones are methods of the state machine, so they require a struct.
+		structs:   wiz.structs || snapshots || clones || hasDirective(fdecl, structDirective),
+		snapshots: snapshots,
+		clones:    clones,
+	}
+	funcWiz.reserveNames()
+	return funcWiz
+}
+
+// The directive requesting a struct-based state machine for a single generator.
+const structDirective = "//gengen:struct"
+
+// hasDirective checks whether the doc comment of a generator contains a gengen directive.
+func hasDirective(fdecl *ast.FuncDecl, directive string) bool {
+	if fdecl.Doc == nil {
+		return false
+	}
+	for _, comment := range fdecl.Doc.List {
+		if strings.TrimSpace(comment.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// The names used by the generated code itself.
+var generatedNames = []string{"__next", "__value", "__err", "__gen"}
+
+// The methods of the struct-based state machine, which its fields must not collide with.
+var iteratorMethods = []string{"Next", "Value", "Error"}
+
+// The methods of state machines that can be snapshotted.
+var snapshotMethods = []string{"Snapshot", "Restore"}
+
+// The method of state machines that can be cloned.
+const cloneMethod = "Clone"
+
+// reserveNames reserves all the names that must not be shadowed by local variables
+// once they are hoisted to the function scope: package-level names, imported package names,
+// predeclared names, type parameters, and the names used by the generated code.
+func (wiz *FuncWizard) reserveNames() {
+	for _, name := range types.Universe.Names() {
+		wiz.names[name] = true
+	}
+	for _, name := range wiz.pkg.Types.Scope().Names() {
+		wiz.names[name] = true
+	}
+	for name := range wiz.imports.importNames {
+		wiz.names[name] = true
+	}
+	for _, name := range generatedNames {
+		wiz.names[name] = true
+	}
+	if wiz.structs {
+		for _, name := range iteratorMethods {
+			wiz.names[name] = true
+		}
+	}
+	if wiz.snapshots {
+		for _, name := range snapshotMethods {
+			wiz.names[name] = true
+		}
+	}
+	if wiz.clones {
+		wiz.names[cloneMethod] = true
+	}
+	// Names declared in function literals are not renamed, so hoisted variables must not shadow them.
+	ast.Inspect(wiz.fdecl.Body, func(node ast.Node) bool {
+		if funcLit, isFuncLit := node.(*ast.FuncLit); isFuncLit {
+			ast.Inspect(funcLit, func(node ast.Node) bool {
+				if ident, isIdent := node.(*ast.Ident); isIdent && wiz.pkg.TypesInfo.Defs[ident] != nil {
+					wiz.names[ident.Name] = true
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+	signature := wiz.pkg.TypesInfo.Defs[wiz.fdecl.Name].Type().(*types.Signature)
+	for _, typeParams := range []*types.TypeParamList{signature.TypeParams(), signature.RecvTypeParams()} {
+		for i := 0; i < typeParams.Len(); i++ {
+			wiz.names[typeParams.At(i).Obj().Name()] = true
+		}
 	}
 }
 
@@ -106,9 +232,39 @@ type FuncWizard struct {
 	names       map[string]bool
 	jumpId      int
 	adapterId   int
-	extraState  []string
+	extraState  map[string]string
+	extraLocals map[string]string
 	loopStack   []LoopFrame
 	blockStack  []Block
+	// The label of the loop statement about to be lowered
+	loopLabel types.Object
+	// Variables that get a fresh copy on every loop iteration
+	boxed map[types.Object]bool
+	// Variables that keep their values between calls to the advance function
+	hoisted map[types.Object]bool
+	// Allocations of fresh copies of boxed variables, pending before the current statement
+	allocations []string
+	// Generate a struct-based state machine instead of a closure
+	structs bool
+	// Generate Snapshot and Restore methods for the struct-based state machine
+	snapshots bool
+	// Generate a Clone method for the struct-based state machine
+	clones bool
+	// The fields holding the function arguments in the struct-based state machine
+	arguments []Argument
+	// The package-level declarations of the local types and constants of the generator
+	declarations []string
+	// Local types that are parameterized by the type parameters of the generator once hoisted
+	generic map[types.Object]bool
+	// The line directives of where each state resumes from - the generator itself, then its yields
+	resumes []string
+}
+
+// Argument is a function argument, stored in a field of the struct-based state machine.
+type Argument struct {
+	Field string
+	Name  string
+	Type  string
 }
 
 func (wiz *FuncWizard) EnterBlock() *FuncWizard {
@@ -144,40 +300,134 @@ func (n *Namer) Name() string {
 }
 
 func (wiz *FuncWizard) AddFunctionArgument(obj types.Object) {
+	if wiz.structs && obj.Name() != "_" {
+		// Arguments are only referred to by their fields, which keep their names when possible.
+		field := wiz.FreshName(obj.Name())
+		wiz.variables[obj] = wiz.StateRef(field)
+		wiz.arguments = append(wiz.arguments, Argument{Field: field, Name: obj.Name(), Type: wiz.getTypeName(obj.Type())})
+		return
+	}
 	wiz.variables[obj] = obj.Name()
 	wiz.names[obj.Name()] = true
 }
 
-func (wiz *FuncWizard) DefineVariable(obj types.Object) (name string) {
-	if obj.Name() == "_" {
-		return "_"
+// StateRef returns the expression referring to a variable that is kept between calls to the
+// advance function.
+func (wiz *FuncWizard) StateRef(name string) string {
+	if wiz.structs {
+		return "__gen." + name
 	}
-	namer := Namer{name: obj.Name()}
+	return name
+}
+
+// FreshName returns a name based on the given name, that does not collide with any other name
+// used in the function.
+func (wiz *FuncWizard) FreshName(name string) string {
+	namer := Namer{name: name}
 	for wiz.names[namer.Name()] {
 		namer.Next()
 	}
 	wiz.names[namer.Name()] = true
-	wiz.definitions[obj] = namer.Name()
-	wiz.variables[obj] = namer.Name()
 	return namer.Name()
 }
 
+// FreshPackageName returns a package-level name based on name, that is used neither by the package
+// nor by the code generated for it.
+// Generated names can collide even when the names they are based on do not - a function named
+// `Tree_Walk` and a `Walk` method of `Tree` are both named `Tree_Walk` when qualified.
+func (wiz *PkgWizard) FreshPackageName(name string) string {
+	namer := Namer{name: name}
+	for !wiz.isFreePackageName(namer.Name()) {
+		namer.Next()
+	}
+	wiz.generated[wiz.pkg.PkgPath][namer.Name()] = true
+	return namer.Name()
+}
+
+// isFreePackageName checks whether a package-level name is still free.
+func (wiz *PkgWizard) isFreePackageName(name string) bool {
+	return !wiz.generated[wiz.pkg.PkgPath][name] && wiz.pkg.Types.Scope().Lookup(name) == nil
+}
+
+func (wiz *FuncWizard) DefineVariable(obj types.Object) (name string) {
+	if obj.Name() == "_" {
+		return "_"
+	}
+	name = wiz.FreshName(obj.Name())
+	wiz.definitions[obj] = name
+	wiz.variables[obj] = name
+	if wiz.hoisted[obj] {
+		wiz.variables[obj] = wiz.StateRef(name)
+	}
+	return wiz.variables[obj]
+}
+
+// DeclareVariable defines a variable on its declaration, and returns the expression to assign its
+// initial value to.
+// Boxed variables get a fresh copy on every declaration, which is allocated before the statement.
+func (wiz *FuncWizard) DeclareVariable(obj types.Object) string {
+	name := wiz.DefineVariable(obj)
+	if name == "_" || !wiz.boxed[obj] {
+		return name
+	}
+	wiz.allocations = append(wiz.allocations, fmt.Sprintf("%s = new(%s)", name, wiz.getTypeName(obj.Type())))
+	return "(*" + name + ")"
+}
+
+// TakeAllocations returns the pending allocations of boxed variables, to be placed before the
+// statement declaring them.
+func (wiz *FuncWizard) TakeAllocations() string {
+	if len(wiz.allocations) == 0 {
+		return ""
+	}
+	allocations := strings.Join(wiz.allocations, "\n") + "\n"
+	wiz.allocations = nil
+	return allocations
+}
+
+// IsLocal checks whether an object is declared within the function.
+func (wiz *FuncWizard) IsLocal(obj types.Object) bool {
+	funcScope := wiz.pkg.TypesInfo.Scopes[wiz.fdecl.Type]
+	for scope := obj.Parent(); scope != nil; scope = scope.Parent() {
+		if scope == funcScope {
+			return true
+		}
+	}
+	return false
+}
+
 func (wiz *FuncWizard) GetVariable(obj types.Object) (name string) {
 	if obj.Name() == "_" {
 		return "_"
 	}
 	name, exists := wiz.variables[obj]
 	if exists {
+		if wiz.boxed[obj] {
+			return "(*" + name + ")"
+		}
 		return name
 	}
-	isPackageName := wiz.importNames[obj.Name()]
-	if isPackageName {
+	if !wiz.IsLocal(obj) {
+		// Package-level and predeclared names are never shadowed, so they keep their names.
+		return obj.Name()
+	}
+	if _, isTypeParam := obj.Type().(*types.TypeParam); isTypeParam {
+		// Type parameters are reserved names, so they keep their names as well.
 		return obj.Name()
 	}
 
 	panic(fmt.Sprintf("No variable for %s", obj.Name()))
 }
 
+// TemplateName returns the name of the template rendering the given construct, which depends on
+// the form of the generated state machine.
+func (wiz *FuncWizard) TemplateName(name string) string {
+	if wiz.structs {
+		return "struct-" + name
+	}
+	return name
+}
+
 func (wiz *FuncWizard) StateIndices() []int {
 	indices := make([]int, wiz.maxState+1)
 	for i := range indices {
@@ -186,6 +436,15 @@ func (wiz *FuncWizard) StateIndices() []int {
 	return indices
 }
 
+// NextLabel returns the label the advance function resumes from in the given state.
+func (wiz *FuncWizard) NextLabel(index int) string {
+	label, err := wiz.Render("next", index)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return strings.TrimSpace(string(label))
+}
+
 func (wiz *FuncWizard) NextIndex() int {
 	wiz.maxState += 1
 	return wiz.maxState
@@ -221,6 +480,11 @@ func (wiz *FuncWizard) convertFunction() []byte {
 
 	// Add all function arguments to the function
 	// Otherwise - we won't have names for them!
+	if wiz.fdecl.Recv != nil {
+		for _, name := range wiz.fdecl.Recv.List[0].Names {
+			wiz.AddFunctionArgument(wiz.pkg.TypesInfo.Defs[name])
+		}
+	}
 	for _, param := range wiz.fdecl.Type.Params.List {
 		for _, name := range param.Names {
 			def := wiz.pkg.TypesInfo.Defs[name]
@@ -228,36 +492,165 @@ func (wiz *FuncWizard) convertFunction() []byte {
 		}
 	}
 
-	var body strings.Builder
+	wiz.analyzeVariables()
+
+	if diagnostics := checkYields(wiz.pkg, wiz.fdecl.Body); len(diagnostics) != 0 {
+		log.Fatal(strings.Join(diagnostics, "\n"))
+	}
+	if wiz.snapshots {
+		if diagnostics := wiz.checkSnapshot(generatorItemType); len(diagnostics) != 0 {
+			log.Fatal(strings.Join(diagnostics, "\n"))
+		}
+	}
+	if wiz.clones {
+		if diagnostics := wiz.checkClone(); len(diagnostics) != 0 {
+			log.Fatal(strings.Join(diagnostics, "\n"))
+		}
+	}
+
+	body := Instructions{&Label{Leading: Leading{wiz.LineDirective(wiz.fdecl)}, Name: wiz.NextLabel(0)}}
 	for _, node := range wiz.fdecl.Body.List {
-		body.WriteString(wiz.convertAst(node))
-		body.WriteString("\n")
+		body = append(body, wiz.lowerStmt(node)...)
+	}
+	entries := make(map[string]bool)
+	for _, index := range wiz.StateIndices() {
+		entries[wiz.NextLabel(index)] = true
+	}
+	body = body.Simplify(entries)
+	if err := body.CheckJumps(entries); err != nil {
+		log.Fatalf("%s: Invalid generated code: %s", wiz.pkg.Fset.Position(wiz.fdecl.Pos()), err)
 	}
 
 	variables := make(map[string]string)
-
+	locals := make(map[string]string)
+	// The state that is held by pointers, which must be copied when cloning the generator
+	var pointers []string
+	// The types of the hoisted boxed variables, which are snapshotted by value
+	boxed := make(map[string]string)
 	for obj, name := range wiz.definitions {
+		typeName := wiz.getTypeName(obj.Type())
+		if wiz.boxed[obj] {
+			if wiz.hoisted[obj] {
+				boxed[name] = typeName
+			}
+			typeName = "*" + typeName
+		}
+		if wiz.hoisted[obj] {
+			variables[name] = typeName
+			if wiz.boxed[obj] {
+				pointers = append(pointers, name)
+			}
+		} else {
+			locals[name] = typeName
+		}
+	}
 
-		variables[name] = wiz.getTypeName(obj.Type())
+	for name, typeName := range wiz.extraState {
+		variables[name] = typeName
+		// Adapters are pointers as well.
+		pointers = append(pointers, name)
+	}
+	sort.Strings(pointers)
+	for name, typeName := range wiz.extraLocals {
+		locals[name] = typeName
 	}
 
-	src, err := wiz.Render("function", struct {
-		Name         string
-		Signature    string
-		ReturnType   string
-		Body         string
-		State        map[string]string
-		StateIndices []int
-		ExtraState   []string
-	}{
-		Name:         wiz.fdecl.Name.Name,
-		Signature:    signature,
-		ReturnType:   returnType,
-		Body:         body.String(),
-		State:        variables,
-		StateIndices: wiz.StateIndices(),
-		ExtraState:   wiz.extraState,
-	})
+	var receiver string
+	if wiz.fdecl.Recv != nil {
+		var recvType bytes.Buffer
+		format.Node(&recvType, wiz.pkg.Fset, wiz.fdecl.Recv.List[0].Type)
+		receiver = fmt.Sprintf("(%s) ", strings.TrimSpace(identNames(wiz.fdecl.Recv.List[0].Names)+" "+recvType.String()))
+	}
+
+	var src []byte
+	var err error
+	if wiz.structs {
+		typeParams, typeArgs := wiz.typeParams()
+		var snapshotType, encodeSnapshot, decodeSnapshot string
+		if wiz.snapshots {
+			snapshotType = wiz.snapshotTypeName()
+			encodeSnapshot, decodeSnapshot = wiz.Gengen("EncodeSnapshot"), wiz.Gengen("DecodeSnapshot")
+		}
+		var iterator, clonePointer string
+		if wiz.clones {
+			iterator, clonePointer = wiz.Gengen("Iterator"), wiz.Gengen("ClonePointer")
+		}
+		src, err = wiz.Render("struct-function", struct {
+			Source         string
+			Generated      string
+			FromIterator   string
+			EncodeSnapshot string
+			DecodeSnapshot string
+			SnapshotType   string
+			Boxed          map[string]string
+			Iterator       string
+			ClonePointer   string
+			Pointers       []string
+			Receiver       string
+			Name           string
+			Signature      string
+			ReturnType     string
+			Type           string
+			TypeParams     string
+			TypeArgs       string
+			Arguments      []Argument
+			Body           string
+			State          map[string]string
+			Resumes        []string
+			Locals         map[string]string
+			Declarations   []string
+		}{
+			Source:         wiz.LineDirective(wiz.fdecl),
+			Generated:      wiz.GeneratedLineDirective(),
+			FromIterator:   wiz.Gengen("FromIterator"),
+			EncodeSnapshot: encodeSnapshot,
+			DecodeSnapshot: decodeSnapshot,
+			SnapshotType:   snapshotType,
+			Boxed:          boxed,
+			Iterator:       iterator,
+			ClonePointer:   clonePointer,
+			Pointers:       pointers,
+			Receiver:       receiver,
+			Name:           wiz.fdecl.Name.Name,
+			Signature:      signature,
+			ReturnType:     returnType,
+			Type:           wiz.structTypeName(),
+			TypeParams:     typeParams,
+			TypeArgs:       typeArgs,
+			Arguments:      wiz.arguments,
+			Body:           body.String(),
+			State:          variables,
+			Resumes:        wiz.resumes,
+			Locals:         locals,
+			Declarations:   wiz.declarations,
+		})
+	} else {
+		src, err = wiz.Render("function", struct {
+			Source        string
+			MakeGenerator string
+			Receiver      string
+			Name          string
+			Signature     string
+			ReturnType    string
+			Body          string
+			State         map[string]string
+			Resumes       []string
+			Locals        map[string]string
+			Declarations  []string
+		}{
+			Source:        wiz.LineDirective(wiz.fdecl),
+			MakeGenerator: wiz.Gengen("MakeGenerator"),
+			Receiver:      receiver,
+			Name:          wiz.fdecl.Name.Name,
+			Signature:     signature,
+			ReturnType:    returnType,
+			Body:          body.String(),
+			State:         variables,
+			Resumes:       wiz.resumes,
+			Locals:        locals,
+			Declarations:  wiz.declarations,
+		})
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -266,429 +659,614 @@ func (wiz *FuncWizard) convertFunction() []byte {
 	return src
 }
 
-func (wiz *FuncWizard) getTypeName(typ types.Type) string {
-	if namedType, isNamedType := typ.(*types.Named); isNamedType {
-		if namedType.Obj().Pkg() == nil {
-			return namedType.Obj().Name()
-		}
-		if namedType.Obj().Pkg() == wiz.pkg.Types {
-			// The type was defined in the same package, so we don't need to name the package
-			// it was imported from.
-			return namedType.Obj().Name()
+// analyzeVariables finds the variables of the generator that are boxed, and the ones that are hoisted.
+func (wiz *FuncWizard) analyzeVariables() {
+	wiz.boxed = findBoxedVariables(wiz.pkg.TypesInfo, wiz.fdecl.Body)
+	// Variables that are not live across yields can be regular locals of the advance function.
+	// Escaping variables may be accessed without naming them, so we always hoist them.
+	wiz.hoisted = findLiveAcrossYields(wiz.pkg.TypesInfo, wiz.fdecl.Body)
+	for obj := range findEscapingVariables(wiz.pkg.TypesInfo, wiz.fdecl.Body) {
+		wiz.hoisted[obj] = true
+	}
+}
+
+// identNames returns the comma-separated names of the given identifiers.
+func identNames(idents []*ast.Ident) string {
+	names := make([]string, len(idents))
+	for i, ident := range idents {
+		names[i] = ident.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// qualifiedName returns the name of the generator, prefixed by the name of its receiver type
+// for methods, to base package-level names on.
+func (wiz *FuncWizard) qualifiedName() string {
+	name := wiz.fdecl.Name.Name
+	signature := wiz.pkg.TypesInfo.Defs[wiz.fdecl.Name].Type().(*types.Signature)
+	if recv := signature.Recv(); recv != nil {
+		recvType := recv.Type()
+		if pointer, isPointer := recvType.(*types.Pointer); isPointer {
+			recvType = pointer.Elem()
 		}
-		packageName, exists := wiz.imports[namedType.Obj().Pkg().Path()]
-		if exists {
-			return fmt.Sprintf("%s.%s", packageName, namedType.Obj().Name())
+		if named, isNamed := recvType.(*types.Named); isNamed {
+			name = named.Obj().Name() + "_" + name
 		}
 	}
-	return typ.String()
+	return name
+}
+
+// structTypeName returns a package-level name for the struct-based state machine of the generator.
+func (wiz *FuncWizard) structTypeName() string {
+	return wiz.FreshPackageName(fmt.Sprintf("__%sGenerator", wiz.qualifiedName()))
+}
+
+// snapshotTypeName returns a package-level name for the serialized state of the generator.
+func (wiz *FuncWizard) snapshotTypeName() string {
+	return wiz.FreshPackageName(fmt.Sprintf("__%sSnapshot", wiz.qualifiedName()))
+}
+
+// typeParams returns the type parameter list of package-level declarations generated for the
+// generator, such as its struct-based state machine, and the type arguments instantiating them
+// inside the generator.
+// These are the type parameters of the function, or of its receiver.
+func (wiz *FuncWizard) typeParams() (params string, args string) {
+	signature := wiz.pkg.TypesInfo.Defs[wiz.fdecl.Name].Type().(*types.Signature)
+	typeParams := signature.TypeParams()
+	if typeParams == nil || typeParams.Len() == 0 {
+		typeParams = signature.RecvTypeParams()
+	}
+	if typeParams == nil || typeParams.Len() == 0 {
+		return "", ""
+	}
+	var paramList, argList []string
+	for i := 0; i < typeParams.Len(); i++ {
+		typeParam := typeParams.At(i)
+		paramList = append(paramList, typeParam.Obj().Name()+" "+wiz.getConstraint(typeParam))
+		argList = append(argList, typeParam.Obj().Name())
+	}
+	return "[" + strings.Join(paramList, ", ") + "]", "[" + strings.Join(argList, ", ") + "]"
 }
 
-func (wiz *FuncWizard) GenericAstVisitor() string { return "" }
-func (wiz *FuncWizard) VisitReturnStmt(node *ast.ReturnStmt) string {
+func (wiz *FuncWizard) GenericAstVisitor() Instructions { return nil }
+func (wiz *FuncWizard) VisitReturnStmt(node *ast.ReturnStmt) Instructions {
 	if len(node.Results) != 1 {
 		log.Fatalf("Expected 1 result, got %d", len(node.Results))
 	}
-	var retval bytes.Buffer
-	err := format.Node(&retval, wiz.pkg.Fset, node.Results[0])
-	if err != nil {
-		log.Fatal(err)
-	}
+	retval := wiz.renderExpr(node.Results[0])
 
-	returnStatement, err := wiz.Render("return", struct{ ReturnValue string }{ReturnValue: retval.String()})
+	returnStatement, err := wiz.Render(wiz.TemplateName("return"), struct{ ReturnValue string }{ReturnValue: retval})
 	if err != nil {
 		log.Fatal(err)
 	}
 	wiz.MarkReturn()
-	return string(returnStatement)
-}
-func (wiz *FuncWizard) VisitCallExpr(node *ast.CallExpr) string {
-	if fun, isSelectorExpr := node.Fun.(*ast.SelectorExpr); isSelectorExpr {
-		object := wiz.pkg.TypesInfo.Uses[fun.Sel]
-		funcObject, isFunc := object.(*types.Func)
-		if isFunc && funcObject.FullName() == YieldType.String() {
-			// If we're after a return statement, we ignore this yield.
-			if wiz.AfterReturn() {
-				return ""
-			}
-			// Yield only accepts one argument
-			if len(node.Args) != 1 {
-				log.Fatal("Yield accepts a single argument.")
-			}
-			yieldValue := wiz.convertAst(node.Args[0])
-
-			yield, err := wiz.Render("yield", struct {
-				YieldValue string
-				Next       int
-			}{
-				YieldValue: yieldValue,
-				Next:       wiz.NextIndex(),
-			})
-			if err != nil {
-				log.Fatal(err)
-			}
-			return string(yield)
-		}
-	}
-	args := make([]string, len(node.Args))
-	for i := range args {
-		args[i] = wiz.convertAst(node.Args[i])
-	}
-	return wiz.convertAst(node.Fun) + "(" + strings.Join(args, ", ") + ")"
-
+	return Instructions{&Return{Text: string(returnStatement)}}
 }
-func (wiz *FuncWizard) VisitExprStmt(node *ast.ExprStmt) string {
-	return wiz.convertAst(node.X)
-}
-func (wiz *FuncWizard) VisitIdent(node *ast.Ident) string {
-	/*
-		Check defs & uses
-		If this is a def - define the var, get the possibly new name
-		If a use - get the name based on the uses object
-	*/
-	if node.Name == "nil" {
-		return node.String()
+func (wiz *FuncWizard) VisitExprStmt(node *ast.ExprStmt) Instructions {
+	if call, isCall := node.X.(*ast.CallExpr); isCall && isYieldCall(wiz.pkg.TypesInfo, call) {
+		return wiz.lowerYield(call)
 	}
-	definition, exists := wiz.pkg.TypesInfo.Defs[node]
-	if exists {
-		return wiz.DefineVariable(definition)
+	return Instructions{&Code{Text: wiz.printNode(&ast.ExprStmt{X: wiz.convertExpr(node.X)})}}
+}
+
+func (wiz *FuncWizard) lowerYield(node *ast.CallExpr) Instructions {
+	// If we're after a return statement, we ignore this yield.
+	if wiz.AfterReturn() {
+		return nil
 	}
-	usage, exists := wiz.pkg.TypesInfo.Uses[node]
-	if _, isBuiltin := usage.(*types.Builtin); isBuiltin {
-		return node.String()
+	// Yield only accepts one argument
+	if len(node.Args) != 1 {
+		log.Fatal("Yield accepts a single argument.")
 	}
-	if exists && usage.Pkg() != nil && usage.Pkg().Path() == wiz.pkg.PkgPath {
-		funcDef := wiz.pkg.TypesInfo.Defs[wiz.fdecl.Name]
-		if funcDef.Parent() == usage.Parent() {
-			// This is a hack for globals, since we don't currently handle them properly.
-			// We check if the name is defined in the same scope as the current function.
-			// If it is, we don't need to rename it.
-			// This is broken, and we need to do proper name handling, but it'll hold for now.
-			return node.Name
-		}
-		return wiz.GetVariable(usage)
+	yieldValue := wiz.renderExpr(node.Args[0])
+	next := wiz.NextIndex()
+	resume := wiz.LineDirective(node)
+	wiz.resumes = append(wiz.resumes, resume)
+
+	yield, err := wiz.Render(wiz.TemplateName("yield"), struct {
+		YieldValue string
+		Next       int
+	}{
+		YieldValue: yieldValue,
+		Next:       next,
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
-	return node.String()
+	// Resuming continues from the yield.
+	return Instructions{&Return{Text: string(yield)}, &Label{Leading: Leading{resume}, Name: wiz.NextLabel(next)}}
 }
-func (wiz *FuncWizard) VisitAssignStmt(node *ast.AssignStmt) string {
-	var lhs []string
+func (wiz *FuncWizard) VisitAssignStmt(node *ast.AssignStmt) Instructions {
+	assign := &ast.AssignStmt{Tok: node.Tok}
+	if assign.Tok == token.DEFINE {
+		// All variables are declared ahead of time
+		assign.Tok = token.ASSIGN
+	}
 	for _, expr := range node.Lhs {
-		lhs = append(lhs, wiz.convertAst(expr))
+		assign.Lhs = append(assign.Lhs, wiz.convertExpr(expr))
 	}
-
-	var rhs []string
 	for _, expr := range node.Rhs {
-		rhs = append(rhs, wiz.convertAst(expr))
+		assign.Rhs = append(assign.Rhs, wiz.convertExpr(expr))
 	}
 
-	tok := node.Tok.String()
-	if tok == ":=" {
-		tok = "="
+	assignment := wiz.printNode(assign)
+	if allocations := wiz.TakeAllocations(); allocations != "" {
+		assignment = allocations + wiz.LineDirective(node) + "\n" + assignment
 	}
-
-	return strings.Join(lhs, ", ") + " " + tok + " " + strings.Join(rhs, ", ")
+	return Instructions{&Code{Text: assignment}}
 }
-func (wiz *FuncWizard) VisitBasicLit(node *ast.BasicLit) string {
-	var lit bytes.Buffer
-	err := format.Node(&lit, wiz.pkg.Fset, node)
-	if err != nil {
-		log.Fatal(err)
+func (wiz *FuncWizard) VisitForStmt(node *ast.ForStmt) Instructions {
+	defer wiz.EnterLoop().ExitLoop()
+	loop := wiz.GetLoopFrame()
+
+	code := wiz.lowerStmt(node.Init)
+	code = append(code, &Label{Name: loop.Head()})
+	if node.Cond != nil {
+		code = append(code, &Branch{
+			Leading: Leading{wiz.LineDirective(node)},
+			Cond:    wiz.renderExpr(node.Cond),
+			Then:    loop.Body(),
+			Else:    loop.After(),
+		})
+	}
+	code = append(code, &Label{Name: loop.Body()})
+	code = append(code, wiz.lowerStmt(node.Body)...)
+	code = append(code, &Label{Name: loop.Continue()})
+	if copies := wiz.perIterationCopies(node.Init); copies != "" {
+		code = append(code, &Code{Text: copies})
 	}
-	return lit.String()
+	code = append(code, wiz.lowerStmt(node.Post)...)
+	code = append(code, &Goto{Leading: Leading{wiz.LineDirective(node)}, Label: loop.Head()})
+	code = append(code, &Label{Name: loop.After()})
+	return code
 }
 
-func (wiz *FuncWizard) VisitForStmt(node *ast.ForStmt) string {
-	// Easiest case is a forever
-	defer wiz.EnterLoop().ExitLoop()
-	if node.Init == nil && node.Cond == nil && node.Post == nil {
-		body := wiz.convertAst(node.Body)
-		loop, err := wiz.Render("forever", struct {
-			Loop LoopFrame
-			Body string
-		}{Loop: *wiz.GetLoopFrame(), Body: body})
-		if err != nil {
-			log.Fatal(err)
-		}
-		return string(loop)
-	} else {
-		// Regular C-style loop!
-		init := wiz.convertAst(node.Init)
-		cond := wiz.convertAst(node.Cond)
-		post := wiz.convertAst(node.Post)
-		body := wiz.convertAst(node.Body)
-		loop, err := wiz.Render("for", struct {
-			Init string
-			Cond string
-			Post string
-			Body string
-			Loop LoopFrame
-		}{
-			Init: init,
-			Cond: cond,
-			Post: post,
-			Body: body,
-			Loop: *wiz.GetLoopFrame(),
-		})
-		if err != nil {
-			log.Fatal(err)
+// perIterationCopies returns the code creating the copies of the boxed loop variables declared in
+// the init statement of a loop, for the next iteration.
+// Like in Go, the copy is created before the post statement, with the value of the previous iteration.
+func (wiz *FuncWizard) perIterationCopies(init ast.Stmt) string {
+	assign, isAssign := init.(*ast.AssignStmt)
+	if !isAssign || assign.Tok != token.DEFINE {
+		return ""
+	}
+	var copies strings.Builder
+	for _, expr := range assign.Lhs {
+		obj := wiz.pkg.TypesInfo.Defs[expr.(*ast.Ident)]
+		if obj != nil && wiz.boxed[obj] {
+			name := wiz.variables[obj]
+			fmt.Fprintf(&copies, "{\n__copy := *%s\n%s = &__copy\n}\n", name, name)
 		}
-		return string(loop)
 	}
+	return copies.String()
 }
-func (wiz *FuncWizard) VisitBlockStmt(node *ast.BlockStmt) string {
+
+func (wiz *FuncWizard) VisitBlockStmt(node *ast.BlockStmt) Instructions {
 	defer wiz.EnterBlock().LeaveBlock()
-	block := make([]string, len(node.List))
-	for i, stmt := range node.List {
-		block[i] = wiz.convertAst(stmt)
+	var code Instructions
+	for _, stmt := range node.List {
+		code = append(code, wiz.lowerStmt(stmt)...)
 	}
-	return strings.Join(block, "\n")
+	return code
 }
-func (wiz *FuncWizard) VisitBinaryExpr(node *ast.BinaryExpr) string {
-	x := wiz.convertAst(node.X)
-	y := wiz.convertAst(node.Y)
-	tok := node.Op.String()
-	return fmt.Sprintf("%s %s %s", x, tok, y)
+func (wiz *FuncWizard) VisitIncDecStmt(node *ast.IncDecStmt) Instructions {
+	return Instructions{&Code{Text: wiz.printNode(&ast.IncDecStmt{X: wiz.convertExpr(node.X), Tok: node.Tok})}}
 }
-func (wiz *FuncWizard) VisitIncDecStmt(node *ast.IncDecStmt) string {
-	x := wiz.convertAst(node.X)
-	return fmt.Sprintf("%s%s", x, node.Tok)
+
+func (wiz *FuncWizard) VisitSendStmt(node *ast.SendStmt) Instructions {
+	return Instructions{&Code{Text: wiz.printNode(&ast.SendStmt{Chan: wiz.convertExpr(node.Chan), Value: wiz.convertExpr(node.Value)})}}
 }
-func (wiz *FuncWizard) VisitDeclStmt(node *ast.DeclStmt) string {
+func (wiz *FuncWizard) VisitDeclStmt(node *ast.DeclStmt) Instructions {
+	var code Instructions
 	switch decl := node.Decl.(type) {
 	case *ast.GenDecl:
+		switch decl.Tok {
+		case token.TYPE:
+			wiz.hoistTypeDecl(decl)
+			return nil
+		case token.CONST:
+			wiz.hoistConstDecl(decl)
+			return nil
+		}
 		for _, spec := range decl.Specs {
 			switch spec := spec.(type) {
 			case *ast.ImportSpec:
 				log.Fatal("There shouldn't be an import here!")
-			case *ast.TypeSpec:
-				log.Fatal("Neither should we have nested type defs")
 			case *ast.ValueSpec:
-				var assignments []string
-				for i, name := range spec.Names {
-					// First, we need to define the matching variable
-					realName := wiz.DefineVariable(wiz.pkg.TypesInfo.Defs[name])
-
-					// Then, if a value exists, we create an assignment
-					if spec.Values != nil {
-						realValue := wiz.convertAst(spec.Values[i])
-						assignments = append(assignments, fmt.Sprintf("%s = %s", realName, realValue))
+				// First, we need to define the matching variables
+				assign := &ast.AssignStmt{Tok: token.ASSIGN}
+				for _, name := range spec.Names {
+					assign.Lhs = append(assign.Lhs, wiz.convertExpr(name))
+				}
+				allocations := wiz.TakeAllocations()
+				if spec.Values == nil {
+					// Variables are declared once for the entire generator, so they must be reset
+					// to their zero values every time the declaration is executed.
+					// Boxed variables are freshly allocated instead.
+					reset := &ast.AssignStmt{Tok: token.ASSIGN}
+					for i, name := range spec.Names {
+						obj := wiz.pkg.TypesInfo.Defs[name]
+						if obj.Name() == "_" || wiz.boxed[obj] {
+							continue
+						}
+						reset.Lhs = append(reset.Lhs, assign.Lhs[i])
+						reset.Rhs = append(reset.Rhs, wiz.parseExpr(wiz.getZeroValue(obj.Type())))
+					}
+					if len(reset.Lhs) != 0 {
+						allocations += wiz.printNode(reset)
+					}
+					if allocations != "" {
+						code = append(code, &Code{Text: allocations})
 					}
+					continue
 				}
-				return strings.Join(assignments, "\n")
+
+				// Then, if values exist, we assign them
+				for _, value := range spec.Values {
+					assign.Rhs = append(assign.Rhs, wiz.convertExpr(value))
+				}
+				code = append(code, &Code{Text: allocations + wiz.printNode(assign)})
 			}
 		}
 	case *ast.FuncDecl:
 		log.Fatal("Nested functions are currently unsupported.")
 	}
-	return ""
-}
-func (wiz *FuncWizard) VisitIfStmt(node *ast.IfStmt) string {
-	loopId := wiz.GetIfId()
-	init := wiz.convertAst(node.Init)
-	body := wiz.convertAst(node.Body)
-	else_ := wiz.convertAst(node.Else)
-	cond := wiz.convertAst(node.Cond)
-	if_, err := wiz.Render("if", struct {
-		Init string
-		Cond string
-		Else string
-		Body string
-		If   int
-	}{
-		Init: init,
-		Cond: cond,
-		Else: else_,
-		Body: body,
-		If:   loopId,
+	return code
+}
+
+// HoistName defines the package-level name of a local type or constant.
+func (wiz *FuncWizard) HoistName(obj types.Object) string {
+	if obj.Name() == "_" {
+		return "_"
+	}
+	namer := Namer{name: fmt.Sprintf("__%s_%s", wiz.qualifiedName(), obj.Name())}
+	for wiz.names[namer.Name()] || !wiz.isFreePackageName(namer.Name()) {
+		namer.Next()
+	}
+	name := namer.Name()
+	wiz.names[name] = true
+	wiz.generated[wiz.pkg.PkgPath][name] = true
+	wiz.variables[obj] = name
+	return name
+}
+
+// hoistTypeDecl moves local type declarations to the package level, so that hoisted variables
+// can use them.
+// Types that use the type parameters of the generator are parameterized by them.
+func (wiz *FuncWizard) hoistTypeDecl(decl *ast.GenDecl) {
+	typeParams, typeArgs := wiz.typeParams()
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.TypeSpec)
+		obj := wiz.pkg.TypesInfo.Defs[spec.Name]
+		name := wiz.HoistName(obj)
+		params := ""
+		if typeParams != "" && wiz.usesTypeParams(spec.Type) {
+			if spec.Assign.IsValid() {
+				log.Fatalf("%s: Local type aliases cannot use type parameters", wiz.pkg.Fset.Position(spec.Pos()))
+			}
+			wiz.generic[obj] = true
+			wiz.variables[obj] = name + typeArgs
+			params = typeParams
+		}
+		assign := " "
+		if spec.Assign.IsValid() {
+			assign = " = "
+		}
+		wiz.declarations = append(wiz.declarations, fmt.Sprintf(
+			"%s\ntype %s%s%s%s",
+			wiz.LineDirective(spec),
+			name,
+			params,
+			assign,
+			wiz.renderExpr(spec.Type),
+		))
+	}
+}
+
+// usesTypeParams checks whether an expression uses the type parameters of the generator,
+// either directly or through other local types.
+func (wiz *FuncWizard) usesTypeParams(expr ast.Expr) bool {
+	uses := false
+	ast.Inspect(expr, func(node ast.Node) bool {
+		ident, isIdent := node.(*ast.Ident)
+		if !isIdent {
+			return !uses
+		}
+		if typeName, isTypeName := wiz.pkg.TypesInfo.Uses[ident].(*types.TypeName); isTypeName {
+			_, isTypeParam := typeName.Type().(*types.TypeParam)
+			uses = uses || isTypeParam || wiz.generic[typeName]
+		}
+		return !uses
 	})
-	if err != nil {
-		log.Fatal(err)
+	return uses
+}
+
+// hoistConstDecl moves local constant declarations to the package level, keeping them grouped
+// so that iota and implicit repetition keep their meaning.
+func (wiz *FuncWizard) hoistConstDecl(decl *ast.GenDecl) {
+	hoisted := &ast.GenDecl{Tok: token.CONST}
+	for _, spec := range decl.Specs {
+		spec := spec.(*ast.ValueSpec)
+		valueSpec := cloneAst(spec, wiz.rewriteExpr)
+		valueSpec.Doc, valueSpec.Comment = nil, nil
+		for i, name := range spec.Names {
+			valueSpec.Names[i] = ast.NewIdent(wiz.HoistName(wiz.pkg.TypesInfo.Defs[name]))
+		}
+		hoisted.Specs = append(hoisted.Specs, valueSpec)
 	}
-	return string(if_)
+	clearPositions(reflect.ValueOf(hoisted))
+	wiz.declarations = append(wiz.declarations, wiz.LineDirective(decl)+"\n"+wiz.printNode(hoisted))
 }
-func (wiz *FuncWizard) VisitRangeStmt(node *ast.RangeStmt) string {
+
+// VisitIfStmt lowers an if statement along with the else-if statements chained to it, as a single
+// dispatch where all the branches jump to the same exit.
+func (wiz *FuncWizard) VisitIfStmt(node *ast.IfStmt) Instructions {
+	ifId := wiz.GetIfId()
+	after := fmt.Sprintf("__After%d", ifId)
+
+	var code Instructions
+	for clause := node; ; {
+		then := fmt.Sprintf("__Then%d", ifId)
+		else_ := fmt.Sprintf("__Else%d", ifId)
+
+		clauseCode := wiz.lowerStmt(clause.Init)
+		branch := &Branch{Cond: wiz.renderExpr(clause.Cond), Then: then, Else: else_}
+		if clause.Init != nil {
+			branch.Leading = Leading{wiz.LineDirective(clause)}
+		}
+		clauseCode = append(clauseCode, branch, &Label{Name: then})
+		clauseCode = append(clauseCode, wiz.lowerStmt(clause.Body)...)
+		clauseCode = append(clauseCode, &Goto{Label: after}, &Label{Name: else_})
+		if clause != node {
+			// Chained statements are not lowered on their own, so we map them to their source here.
+			*clauseCode[0].leading() = wiz.Comments(clause) + wiz.lineDirectiveFor(clauseCode, clause) + *clauseCode[0].leading()
+		}
+		code = append(code, clauseCode...)
+
+		elseIf, isElseIf := clause.Else.(*ast.IfStmt)
+		if !isElseIf {
+			code = append(code, wiz.lowerStmt(clause.Else)...)
+			break
+		}
+		clause = elseIf
+		ifId = wiz.GetIfId()
+	}
+	code = append(code, &Label{Name: after})
+	return code
+}
+func (wiz *FuncWizard) VisitRangeStmt(node *ast.RangeStmt) Instructions {
 	rangeType := wiz.pkg.TypesInfo.TypeOf(node.X)
 	defer wiz.EnterLoop().ExitLoop()
-	switch rangeType := rangeType.(type) {
+	loop := wiz.GetLoopFrame()
+
+	var adapterName, newAdapter string
+	// Named collection types and type parameters are ranged over like the collections they are made of.
+	switch rangeType := coreType(rangeType).(type) {
 	case *types.Map:
-		x := wiz.convertAst(node.X)
-		keyType := rangeType.Key()
-		valueType := rangeType.Elem()
-		mapAdapterId := wiz.GetAdapterId()
-		adapterName := fmt.Sprintf("__mapAdapter%d", mapAdapterId)
-		mapAdapterDefinition := fmt.Sprintf("var %s *gengen.MapAdapter[%s, %s]", adapterName, keyType, wiz.getTypeName(valueType))
-		wiz.AddStateLine(mapAdapterDefinition)
-		key := "_"
-		value := "_"
-		if node.Key != nil {
-			key = wiz.convertAst(node.Key)
-		}
-		if node.Value != nil {
-			value = wiz.convertAst(node.Value)
-		}
-		body := wiz.convertAst(node.Body)
-		forLoop, err := wiz.Render("for-range-map", struct {
-			Adapter   string
-			Key       string
-			KeyType   string
-			Value     string
-			ValueType string
-			Map       string
-			Loop      LoopFrame
-			Body      string
-		}{
-			Adapter:   adapterName,
-			Key:       key,
-			KeyType:   keyType.String(),
-			Value:     value,
-			ValueType: wiz.getTypeName(valueType),
-			Map:       x,
-			Loop:      *wiz.GetLoopFrame(),
-			Body:      body,
-		})
-		if err != nil {
-			log.Fatal(err)
+		keyType := wiz.getTypeName(rangeType.Key())
+		valueType := wiz.getTypeName(rangeType.Elem())
+		adapter, constructor, args := "MapAdapter", "NewMapAdapter", []ast.Expr{node.X}
+		if sortedConstructor, sortedArgs, isSorted := wiz.sortedMap(node.X); isSorted {
+			adapter, constructor, args = "SortedMapAdapter", sortedConstructor, sortedArgs
 		}
-		return string(forLoop)
+		adapterName = wiz.FreshName(fmt.Sprintf("__mapAdapter%d", wiz.GetAdapterId()))
+		adapterType := fmt.Sprintf("*%s[%s, %s]", wiz.Gengen(adapter), keyType, valueType)
+		adapterName = wiz.AddAdapter(node, adapterName, adapterType)
+		renderedArgs := make([]string, len(args))
+		for i, arg := range args {
+			renderedArgs[i] = wiz.renderExpr(arg)
+		}
+		newAdapter = fmt.Sprintf("%s[%s, %s](%s)", wiz.Gengen(constructor), keyType, valueType, strings.Join(renderedArgs, ", "))
+	case *types.Pointer:
+		// Ranging over a pointer to an array ranges over the array itself, so we slice it in place.
+		array, isArray := rangeType.Elem().Underlying().(*types.Array)
+		if !isArray {
+			return wiz.unsupportedRange(node, rangeType)
+		}
+		valueType := wiz.getTypeName(array.Elem())
+		adapterName = wiz.FreshName(fmt.Sprintf("__sliceAdapter%d", wiz.GetAdapterId()))
+		adapterType := fmt.Sprintf("*%s[%s]", wiz.Gengen("SliceAdapter"), valueType)
+		adapterName = wiz.AddAdapter(node, adapterName, adapterType)
+		slice := fmt.Sprintf("func(array %s) []%s { return array[:] }(%s)", wiz.getTypeName(rangeType), valueType, wiz.renderExpr(node.X))
+		newAdapter = fmt.Sprintf("%s[%s](%s)", wiz.Gengen("NewSliceAdapter"), valueType, slice)
 	case *types.Slice, *types.Array:
-		x := wiz.convertAst(node.X)
-		valueType := rangeType.(interface{ Elem() types.Type }).Elem()
-		mapAdapterId := wiz.GetAdapterId()
-		adapterName := fmt.Sprintf("__sliceAdapter%d", mapAdapterId)
-		mapAdapterDefinition := fmt.Sprintf("var %s *gengen.SliceAdapter[%s]", adapterName, wiz.getTypeName(valueType))
-		wiz.AddStateLine(mapAdapterDefinition)
-		key := "_"
-		value := "_"
-		if node.Key != nil {
-			key = wiz.convertAst(node.Key)
-		}
-		if node.Value != nil {
-			value = wiz.convertAst(node.Value)
-		}
-		body := wiz.convertAst(node.Body)
-		name := wiz.getTypeName(valueType)
-		forLoop, err := wiz.Render("for-range-slice", struct {
-			Adapter   string
-			Key       string
-			KeyType   string
-			Value     string
-			ValueType string
-			Slice     string
-			Loop      LoopFrame
-			Body      string
-		}{
-			Adapter:   adapterName,
-			Key:       key,
-			KeyType:   "int",
-			Value:     value,
-			ValueType: name,
-			Slice:     x,
-			Loop:      *wiz.GetLoopFrame(),
-			Body:      body,
-		})
-		if err != nil {
-			log.Fatal(err)
+		valueType := wiz.getTypeName(rangeType.(interface{ Elem() types.Type }).Elem())
+		adapterName = wiz.FreshName(fmt.Sprintf("__sliceAdapter%d", wiz.GetAdapterId()))
+		adapterType := fmt.Sprintf("*%s[%s]", wiz.Gengen("SliceAdapter"), valueType)
+		adapterName = wiz.AddAdapter(node, adapterName, adapterType)
+		slice := wiz.renderExpr(node.X)
+		if _, isArray := rangeType.(*types.Array); isArray {
+			// Ranging over an array ranges over a copy of it, so we slice a copy.
+			slice = fmt.Sprintf("func(array %s) []%s { return array[:] }(%s)", wiz.getTypeName(rangeType), valueType, slice)
 		}
-		return string(forLoop)
+		newAdapter = fmt.Sprintf("%s[%s](%s)", wiz.Gengen("NewSliceAdapter"), valueType, slice)
 	default:
-		return wiz.Unsupported(node)
+		return wiz.unsupportedRange(node, rangeType)
 	}
 
+	var key, value ast.Expr = ast.NewIdent("_"), ast.NewIdent("_")
+	if node.Key != nil {
+		key = wiz.convertExpr(node.Key)
+	}
+	if node.Value != nil {
+		value = wiz.convertExpr(node.Value)
+	}
+	allocations := wiz.TakeAllocations()
+
+	code := Instructions{
+		&Code{Text: fmt.Sprintf("%s = %s", adapterName, newAdapter)},
+		&Label{Name: loop.Head()},
+		&Label{Name: loop.Continue()},
+		&Branch{
+			Leading: Leading{wiz.LineDirective(node)},
+			Cond:    adapterName + ".Next()",
+			Then:    loop.Body(),
+			Else:    loop.After(),
+		},
+		&Label{Name: loop.Body()},
+		&Code{Text: fmt.Sprintf("%s%s\n%s", allocations, wiz.LineDirective(node), wiz.printNode(&ast.AssignStmt{
+			Lhs: []ast.Expr{key, value},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.SelectorExpr{X: wiz.parseExpr(adapterName), Sel: ast.NewIdent("Value")}}},
+		}))},
+	}
+	code = append(code, wiz.lowerStmt(node.Body)...)
+	code = append(code, &Goto{Label: loop.Head()}, &Label{Name: loop.After()})
+	return code
 }
-func (wiz *FuncWizard) VisitUnaryExpr(node *ast.UnaryExpr) string {
 
-	return node.Op.String() + wiz.convertAst(node.X)
+// unsupportedRange leaves a range statement over an unsupported type as is, or fails if it yields,
+// as its yields would be dropped.
+func (wiz *FuncWizard) unsupportedRange(node *ast.RangeStmt, rangeType types.Type) Instructions {
+	if usesYield(wiz.pkg, node) {
+		log.Fatalf("%s: gengen.Yield is not supported inside range statements over %s", wiz.pkg.Fset.Position(node.Pos()), rangeType)
+	}
+	return Instructions{&Code{Text: wiz.Unsupported(node)}}
 }
-func (wiz *FuncWizard) VisitSelectorExpr(node *ast.SelectorExpr) string {
-	// The selector is always an ast.Ident, so we just take the name.
-	// We cannot use the VisitIdent method as the selector does not represent a variable,
-	// only an identifier.
-	expr := wiz.convertAst(node.X) + "." + node.Sel.Name
-	return expr
+
+// sortedAdapters are the constructors of the adapters used for ranging over the gengen functions
+// that sort maps.
+var sortedAdapters = map[string]string{
+	"Sorted":     "NewSortedMapAdapter",
+	"SortedFunc": "NewSortedMapAdapterFunc",
 }
-func (wiz *FuncWizard) VisitBranchStmt(node *ast.BranchStmt) string {
-	if node.Label != nil {
-		return wiz.Unsupported(node)
+
+// sortedMap checks whether a ranged-over expression is a call to gengen.Sorted or gengen.SortedFunc,
+// and returns the constructor of the adapter to use and its arguments.
+func (wiz *FuncWizard) sortedMap(expr ast.Expr) (constructor string, args []ast.Expr, isSorted bool) {
+	call, isCall := expr.(*ast.CallExpr)
+	if !isCall {
+		return "", nil, false
+	}
+	ident := calleeIdent(call.Fun)
+	if ident == nil {
+		return "", nil, false
 	}
-	switch node.Tok {
-	case token.BREAK:
-		wiz.UseBreak()
-		return fmt.Sprintf("goto __After%d", wiz.GetLoopId())
-	case token.CONTINUE:
-		wiz.UseContinue()
-		return fmt.Sprintf("goto __Continue%d", wiz.GetLoopId())
+	funcObject, isFunc := wiz.pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !isFunc || funcObject.Pkg() == nil || funcObject.Pkg().Path() != GeneratorType.PkgPath {
+		return "", nil, false
 	}
-	return wiz.Unsupported(node)
+	constructor, isSorted = sortedAdapters[funcObject.Name()]
+	return constructor, call.Args, isSorted
 }
-
-func (wiz *FuncWizard) VisitCompositeLit(node *ast.CompositeLit) string {
-	elts := make([]string, len(node.Elts))
-	for i, elt := range node.Elts {
-		elts[i] = wiz.convertAst(elt)
+func (wiz *FuncWizard) VisitBranchStmt(node *ast.BranchStmt) Instructions {
+	if node.Tok == token.GOTO {
+		return Instructions{&Goto{Label: wiz.UserLabel(wiz.pkg.TypesInfo.Uses[node.Label])}}
+	}
+	if node.Tok != token.BREAK && node.Tok != token.CONTINUE {
+		return Instructions{&Code{Text: wiz.Unsupported(node)}}
 	}
 
-	return wiz.convertAst(node.Type) + "{" + strings.Join(elts, ", ") + "}"
-
-}
-func (wiz *FuncWizard) VisitArrayType(node *ast.ArrayType) string {
-	var arrType bytes.Buffer
-	err := format.Node(&arrType, wiz.pkg.Fset, node)
-	if err != nil {
-		log.Fatal(err)
+	var loop *LoopFrame
+	if node.Label == nil {
+		loop = wiz.GetLoopFrame()
+	} else {
+		loop = wiz.GetLabeledLoopFrame(wiz.pkg.TypesInfo.Uses[node.Label])
+		if loop == nil {
+			log.Fatalf("%s: Only loops can be broken out of by label", wiz.pkg.Fset.Position(node.Pos()))
+		}
 	}
-	return arrType.String()
+	if node.Tok == token.BREAK {
+		return Instructions{&Goto{Label: loop.After()}}
+	}
+	return Instructions{&Goto{Label: loop.Continue()}}
 }
 
-func (wiz *FuncWizard) VisitIndexExpr(node *ast.IndexExpr) string {
-	return wiz.convertAst(node.X) + "[" + wiz.convertAst(node.Index) + "]"
+// VisitLabeledStmt lowers a labeled statement.
+// User labels are renamed, so that they don't collide with the labels of the generated code.
+func (wiz *FuncWizard) VisitLabeledStmt(node *ast.LabeledStmt) Instructions {
+	label := wiz.pkg.TypesInfo.Defs[node.Label]
+	// Labels can be jumped to, so the code following them is reachable even after a return statement.
+	wiz.blockStack[len(wiz.blockStack)-1].seenReturn = false
+	switch node.Stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt:
+		wiz.loopLabel = label
+	}
+	code := Instructions{&Label{Name: wiz.UserLabel(label)}}
+	return append(code, wiz.lowerStmt(node.Stmt)...)
 }
 
-func (wiz *FuncWizard) VisitGoStmt(node *ast.GoStmt) string {
-	return "go " + wiz.convertAst(node.Call)
+// UserLabel returns the name of a label of the generator in the generated code.
+func (wiz *FuncWizard) UserLabel(label types.Object) string {
+	return "__Label_" + label.Name()
 }
 
-func (wiz *FuncWizard) VisitChanType(node *ast.ChanType) string {
-	return "chan " + wiz.convertAst(node.Value)
+func (wiz *FuncWizard) VisitGoStmt(node *ast.GoStmt) Instructions {
+	return Instructions{&Code{Text: wiz.printNode(&ast.GoStmt{Call: wiz.convertExpr(node.Call).(*ast.CallExpr)})}}
 }
 
-func (wiz *FuncWizard) convertAst(node ast.Node) string {
+// lowerStmt lowers a statement into instructions.
+func (wiz *FuncWizard) lowerStmt(node ast.Stmt) Instructions {
 	if node == nil {
 		// This saves some work with conditionally-nil nodes.
 		// E.g. ast.IfStmt.Init
-		return ""
+		return nil
 	}
-	visitor := GenericVisit[string](wiz, node)
+	var code Instructions
+	visitor := GenericVisit[Instructions](wiz, node)
 	if visitor != nil {
-		return visitor()
+		code = visitor()
 	} else {
-		return wiz.Unsupported(node)
+		if usesYield(wiz.pkg, node) {
+			kind := strings.TrimSuffix(reflect.TypeOf(node).Elem().Name(), "Stmt")
+			log.Fatalf("%s: gengen.Yield is not supported inside %s statements", wiz.pkg.Fset.Position(node.Pos()), strings.ToLower(kind))
+		}
+		code = Instructions{&Code{Text: wiz.Unsupported(node)}}
+	}
+
+	// Map every lowered statement back to its source position.
+	if len(code) == 0 {
+		code = Instructions{&Code{}}
+	}
+	leading := wiz.Comments(node)
+	if _, isBlock := node.(*ast.BlockStmt); !isBlock {
+		leading += wiz.lineDirectiveFor(code, node)
+	}
+	*code[0].leading() = leading + *code[0].leading()
+	return code
+}
+
+// lineDirectiveFor returns the line directive mapping the code lowered from node back to it, or
+// nothing if the code is already mapped to the same line - as with statements nested on the line
+// of their parent, like the init statement of a loop.
+func (wiz *FuncWizard) lineDirectiveFor(code Instructions, node ast.Node) string {
+	directive := wiz.LineDirective(node)
+	if strings.HasPrefix(*code[0].leading(), directive) {
+		return ""
 	}
+	return directive
 }
 
 func (wiz *FuncWizard) GetLoopFrame() *LoopFrame {
 	return &wiz.loopStack[len(wiz.loopStack)-1]
 }
-func (wiz *FuncWizard) GetLoopId() int {
-	return wiz.GetLoopFrame().Id
-}
 
-func (wiz *FuncWizard) UseContinue() {
-	wiz.GetLoopFrame().HasContinue = true
-}
-func (wiz *FuncWizard) UseBreak() {
-	wiz.GetLoopFrame().HasBreak = true
+// GetLabeledLoopFrame returns the frame of the enclosing loop with the given label, or nil if no
+// enclosing loop has it.
+func (wiz *FuncWizard) GetLabeledLoopFrame(label types.Object) *LoopFrame {
+	for i := len(wiz.loopStack) - 1; i >= 0; i-- {
+		if wiz.loopStack[i].Label == label {
+			return &wiz.loopStack[i]
+		}
+	}
+	return nil
 }
 
 type LoopFrame struct {
-	Id          int
-	HasContinue bool
-	HasBreak    bool
+	Id int
+	// The label of the loop, if it has one
+	Label types.Object
 }
 
+func (loop *LoopFrame) Head() string     { return fmt.Sprintf("__Head%d", loop.Id) }
+func (loop *LoopFrame) Body() string     { return fmt.Sprintf("__Body%d", loop.Id) }
+func (loop *LoopFrame) Continue() string { return fmt.Sprintf("__Continue%d", loop.Id) }
+func (loop *LoopFrame) After() string    { return fmt.Sprintf("__After%d", loop.Id) }
+
 func (wiz *FuncWizard) EnterLoop() *FuncWizard {
 	wiz.jumpId++
 	loopId := wiz.jumpId
-	wiz.loopStack = append(wiz.loopStack, LoopFrame{Id: loopId})
+	wiz.loopStack = append(wiz.loopStack, LoopFrame{Id: loopId, Label: wiz.loopLabel})
+	wiz.loopLabel = nil
 	return wiz
 }
 
@@ -714,6 +1292,13 @@ func (wiz *FuncWizard) GetAdapterId() int {
 	return wiz.adapterId
 }
 
-func (wiz *FuncWizard) AddStateLine(definition string) {
-	wiz.extraState = append(wiz.extraState, definition)
+// AddAdapter defines the adapter of a range loop, and returns the expression referring to it.
+// The adapter only needs to be kept between calls to the advance function if the loop yields.
+func (wiz *FuncWizard) AddAdapter(node *ast.RangeStmt, name string, typeName string) string {
+	if !usesYield(wiz.pkg, node.Body) {
+		wiz.extraLocals[name] = typeName
+		return name
+	}
+	wiz.extraState[name] = typeName
+	return wiz.StateRef(name)
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"golang.org/x/tools/go/packages"
+)
+
+// checkYields returns diagnostics for all the uses of gengen.Yield in a generator body that
+// cannot be lowered.
+//
+// Yield has no result, so the type checker already rejects calls to it inside other expressions.
+// The generator can only be suspended by a call that is a statement of the generator itself:
+// a call in a go or defer statement, or inside a function literal, runs outside of it.
+func checkYields(pkg *packages.Package, body *ast.BlockStmt) []string {
+	info := pkg.TypesInfo
+	var diagnostics []string
+	report := func(node ast.Node, message string) {
+		diagnostics = append(diagnostics, fmt.Sprintf("%s: %s", pkg.Fset.Position(node.Pos()), message))
+	}
+
+	// References to Yield that are already accounted for.
+	checked := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.ExprStmt:
+			if call, isCall := node.X.(*ast.CallExpr); isCall && isYieldCall(info, call) {
+				checked[calleeIdent(call.Fun)] = true
+			}
+		case *ast.GoStmt:
+			if isYieldCall(info, node.Call) {
+				report(node, "gengen.Yield cannot be called in a go statement")
+				checked[calleeIdent(node.Call.Fun)] = true
+			}
+		case *ast.DeferStmt:
+			if isYieldCall(info, node.Call) {
+				report(node, "gengen.Yield cannot be deferred")
+				checked[calleeIdent(node.Call.Fun)] = true
+			}
+		case *ast.FuncLit:
+			ast.Inspect(node.Body, func(node ast.Node) bool {
+				if ident, isIdent := node.(*ast.Ident); isIdent && isYieldFunc(info.Uses[ident]) {
+					report(ident, "gengen.Yield cannot be used inside a function literal")
+				}
+				return true
+			})
+			return false
+		case *ast.Ident:
+			if isYieldFunc(info.Uses[node]) && !checked[node] {
+				report(node, "gengen.Yield can only be called as a statement")
+			}
+		}
+		return true
+	})
+	return diagnostics
+}
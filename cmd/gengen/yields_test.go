@@ -0,0 +1,22 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckYields(t *testing.T) {
+	diagnostics := testdataDiagnostics(t, "yields", func(wiz *FuncWizard) []string {
+		return checkYields(wiz.pkg, wiz.fdecl.Body)
+	})
+
+	want := []string{
+		"yields.go:9: gengen.Yield cannot be called in a go statement",
+		"yields.go:10: gengen.Yield cannot be deferred",
+		"yields.go:11: gengen.Yield can only be called as a statement",
+		"yields.go:14: gengen.Yield cannot be used inside a function literal",
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Errorf("checkYields() = %q, want %q", diagnostics, want)
+	}
+}
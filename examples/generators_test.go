@@ -375,3 +375,63 @@ func TestFilterOut(t *testing.T) {
 		})
 	}
 }
+
+func TestNextDoesNotAllocate(t *testing.T) {
+	slice := make([]int, 10000)
+	// The map has more entries than the measured runs, so it is not exhausted before they are done.
+	dict := make(map[int]string, len(slice))
+	for i := range slice {
+		dict[i] = "a"
+	}
+	tests := []struct {
+		name string
+		gen  gengen.Generator[int]
+	}{
+		{"Fibonacci", Fibonacci()},
+		{"Range", Range(10000)},
+		{"IterIntSlice", IterIntSlice(slice)},
+		{"TakeN", TakeN(10000, Fibonacci())},
+		{"FilterIn", FilterIn(func(n int) bool { return n%2 == 0 }, Range(100000))},
+		{"IterMapKeys", IterMapKeys(dict)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The first call sets up the generator's state
+			tt.gen.Next()
+			allocs := testing.AllocsPerRun(100, func() {
+				if !tt.gen.Next() {
+					t.Fatalf("%s() is exhausted before the measurement is done", tt.name)
+				}
+				tt.gen.Value()
+			})
+			if allocs != 0 {
+				t.Errorf("%s().Next() allocates %v times per call, want 0", tt.name, allocs)
+			}
+		})
+	}
+}
+
+func BenchmarkFibonacci(b *testing.B) {
+	b.ReportAllocs()
+	fibonacci := Fibonacci()
+	for i := 0; i < b.N; i++ {
+		fibonacci.Next()
+	}
+}
+
+func BenchmarkRange(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for intRange := Range(100); intRange.Next(); {
+		}
+	}
+}
+
+func BenchmarkIterIntSlice(b *testing.B) {
+	b.ReportAllocs()
+	slice := make([]int, 100)
+	for i := 0; i < b.N; i++ {
+		for values := IterIntSlice(slice); values.Next(); {
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package gengen
+
+// Iterator defines an interface for iteration.
+// Usage is as follows:
+//
+//		iter := GetIterator()
+//		for iter.Next() {
+//			fmt.Println(iter.Value())
+//		}
+//		if iter.Error() != nil {
+//			panic(iter.Error())
+//		}
+type Iterator[T any] interface {
+	// Next advances the iteration state and returns true if there's another value, false on exhaustion.
+	Next() bool
+	// Value returns the current value of the iterator
+	Value() T
+	// Error returns the termination error of the iterator. Will return nil if the iterator was exhausted
+	// without errors.
+	Error() error
+}
+
+// Iterator2 defines an iterator that returns 2 values instead of one.
+type Iterator2[A, B any] interface {
+	Next() bool
+	Value() (A, B)
+	Error() error
+}
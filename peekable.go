@@ -0,0 +1,97 @@
+package gengen
+
+// Peekable wraps an iterator with lookahead, for consumers such as lexers that need to see
+// the next value before deciding whether to consume it.
+// Peekable implements the Iterator interface.
+//
+//	tokens := gengen.NewPeekable[Token](&gen)
+//	for tokens.NextIf(isSpace) {
+//	}
+//	if next, ok := tokens.Peek(); ok {
+//		fmt.Println(next)
+//	}
+type Peekable[T any] struct {
+	iterator Iterator[T]
+	value    T
+	// Values to return before advancing the iterator, in reverse order.
+	// Peeked values and unread values are both kept here.
+	pending []T
+	// Whether the iterator was exhausted, after which it must not be advanced again
+	exhausted bool
+}
+
+// NewPeekable creates a Peekable wrapping the given iterator.
+func NewPeekable[T any](iterator Iterator[T]) *Peekable[T] {
+	return &Peekable[T]{iterator: iterator}
+}
+
+func (p *Peekable[T]) Next() bool {
+	if n := len(p.pending); n > 0 {
+		p.value = p.pending[n-1]
+		p.pending = p.pending[:n-1]
+		return true
+	}
+	if !p.advance() {
+		return false
+	}
+	p.value = p.iterator.Value()
+	return true
+}
+
+func (p *Peekable[T]) Value() T {
+	return p.value
+}
+
+// Error returns the termination error of the wrapped iterator.
+// The iteration is not over while there are peeked or unread values to return, so Error returns
+// nil until they are consumed.
+func (p *Peekable[T]) Error() error {
+	if len(p.pending) > 0 {
+		return nil
+	}
+	return p.iterator.Error()
+}
+
+// Peek returns the value the next call to Next will advance to, without advancing.
+// If the iteration is over, Peek returns false.
+func (p *Peekable[T]) Peek() (T, bool) {
+	if n := len(p.pending); n > 0 {
+		return p.pending[n-1], true
+	}
+	if !p.advance() {
+		var zero T
+		return zero, false
+	}
+	value := p.iterator.Value()
+	p.pending = append(p.pending, value)
+	return value, true
+}
+
+// Unread pushes a value back, so that the next call to Next advances to it.
+// Values can be unread repeatedly, and are returned in reverse order, even after
+// the wrapped iterator is exhausted.
+// Value keeps returning the current value until the next call to Next.
+func (p *Peekable[T]) Unread(value T) {
+	p.pending = append(p.pending, value)
+}
+
+// NextIf advances to the next value only if it satisfies the predicate, and returns whether it did.
+func (p *Peekable[T]) NextIf(predicate func(T) bool) bool {
+	value, ok := p.Peek()
+	if !ok || !predicate(value) {
+		return false
+	}
+	return p.Next()
+}
+
+// advance advances the wrapped iterator, unless it was already exhausted.
+func (p *Peekable[T]) advance() bool {
+	if p.exhausted {
+		return false
+	}
+	if !p.iterator.Next() {
+		p.exhausted = true
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package gengen
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// iterate returns an iterator over the values, terminating with the given error.
+// It fails the test if it is advanced after it is exhausted.
+func iterate[T any](t *testing.T, values []T, err error) Iterator[T] {
+	index := 0
+	gen := MakeGenerator(func(value *T, errOut *error) bool {
+		if index > len(values) {
+			t.Error("Next() called after exhaustion")
+		}
+		if index >= len(values) {
+			index++
+			*errOut = err
+			return false
+		}
+		*value = values[index]
+		index++
+		return true
+	})
+	return &gen
+}
+
+func Values[T any](iterator Iterator[T]) (values []T) {
+	for iterator.Next() {
+		values = append(values, iterator.Value())
+	}
+	return
+}
+
+func TestPeekablePeek(t *testing.T) {
+	p := NewPeekable(iterate(t, []int{1, 2, 3}, nil))
+	if value, ok := p.Peek(); !ok || value != 1 {
+		t.Errorf("Peek() = %v, %v, want 1, true", value, ok)
+	}
+	if value, ok := p.Peek(); !ok || value != 1 {
+		t.Errorf("Peek() = %v, %v, want 1, true", value, ok)
+	}
+	if !p.Next() || p.Value() != 1 {
+		t.Errorf("Value() = %v, want 1", p.Value())
+	}
+	if value, ok := p.Peek(); !ok || value != 2 {
+		t.Errorf("Peek() = %v, %v, want 2, true", value, ok)
+	}
+	if p.Value() != 1 {
+		t.Errorf("Value() = %v after Peek(), want 1", p.Value())
+	}
+	if got, want := Values[int](p), []int{2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if value, ok := p.Peek(); ok || value != 0 {
+		t.Errorf("Peek() = %v, %v after exhaustion, want 0, false", value, ok)
+	}
+	if p.Next() {
+		t.Error("Next() = true after exhaustion")
+	}
+}
+
+func TestPeekableUnread(t *testing.T) {
+	p := NewPeekable(iterate(t, []string{"a", "b"}, nil))
+	p.Next()
+	p.Unread(p.Value())
+	if p.Value() != "a" {
+		t.Errorf("Value() = %v after Unread(), want a", p.Value())
+	}
+	if value, ok := p.Peek(); !ok || value != "a" {
+		t.Errorf("Peek() = %v, %v, want a, true", value, ok)
+	}
+	if got, want := Values[string](p), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	// Values can be unread after exhaustion, and are returned in reverse order.
+	p.Unread("y")
+	p.Unread("x")
+	if got, want := Values[string](p), []string{"x", "y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestPeekableNextIf(t *testing.T) {
+	isSpace := func(r rune) bool { return r == ' ' }
+	p := NewPeekable(iterate(t, []rune("  ab "), nil))
+	skipped := 0
+	for p.NextIf(isSpace) {
+		skipped++
+	}
+	if skipped != 2 {
+		t.Errorf("skipped %d spaces, want 2", skipped)
+	}
+	if p.NextIf(isSpace) {
+		t.Error("NextIf() = true for a")
+	}
+	if got, want := string(Values[rune](p)), "ab "; got != want {
+		t.Errorf("got = %q, want %q", got, want)
+	}
+	if p.NextIf(func(rune) bool { return true }) {
+		t.Error("NextIf() = true after exhaustion")
+	}
+}
+
+func TestPeekableError(t *testing.T) {
+	errDone := errors.New("done")
+	p := NewPeekable(iterate(t, []int{1}, errDone))
+	if _, ok := p.Peek(); !ok || p.Error() != nil {
+		t.Errorf("Error() = %v while iterating, want nil", p.Error())
+	}
+	p.Next()
+	if _, ok := p.Peek(); ok {
+		t.Error("Peek() = true after exhaustion")
+	}
+	if !errors.Is(p.Error(), errDone) {
+		t.Errorf("Error() = %v, want %v", p.Error(), errDone)
+	}
+	p.Unread(1)
+	if p.Error() != nil {
+		t.Errorf("Error() = %v with unread values, want nil", p.Error())
+	}
+	if got, want := Values[int](p), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if !errors.Is(p.Error(), errDone) {
+		t.Errorf("Error() = %v, want %v", p.Error(), errDone)
+	}
+}
@@ -6,65 +6,80 @@ package gengen
 // In normal Go code it does nothing.
 func Yield(value any) {}
 
-// Iterator defines an interface for iteration.
-// Usage is as follows:
-//
-//		iter := GetIterator()
-//		for iter.Next() {
-//			fmt.Println(iter.Value())
-//		}
-//		if iter.Error() != nil {
-//			panic(iter.Error())
-//		}
-type Iterator[T any] interface {
-	// Next advances the iteration state and returns true if there's another value, false on exhaustion.
-	Next() bool
-	// Value returns the current value of the iterator
-	Value() T
-	// Error returns the termination error of the iterator. Will return nil if the iterator was exhausted
-	// without errors.
-	Error() error
-}
-
-// Iterator2 defines an iterator that returns 2 values instead of one.
-type Iterator2[A, B any] interface {
-	Next() bool
-	Value() (A, B)
-	Error() error
-}
-
 // Generator is the type returned from generator functions.
 // Generator implements the Iterator interface.
 // It is used by code-generation and not intended for manual creation.
 type Generator[T any] struct {
-	advance func(withValue func(value T) bool, withError func(err error) bool, exhausted func() bool) bool
+	advance func(value *T, err *error) bool
 	value   T
 	err     error
+	// Generators created from a state machine iterator delegate to it
+	iterator Iterator[T]
 }
 
 func (it *Generator[T]) Value() T {
+	if it.iterator != nil {
+		return it.iterator.Value()
+	}
 	return it.value
 }
 
 func (it *Generator[T]) Error() error {
+	if it.iterator != nil {
+		return it.iterator.Error()
+	}
 	return it.err
 }
 
 func (it *Generator[T]) Next() bool {
-	withValue := func(value T) bool {
-		it.value = value
-		return true
+	if it.iterator != nil {
+		return it.iterator.Next()
+	}
+	return it.advance(&it.value, &it.err)
+}
+
+// Snapshot returns the serialized state of the generator, to resume it from later using Restore.
+// Only generators generated in snapshot mode support snapshots, others return ErrNoSnapshots.
+func (it *Generator[T]) Snapshot() ([]byte, error) {
+	snapshotter, isSnapshotter := it.iterator.(Snapshotter)
+	if !isSnapshotter {
+		return nil, ErrNoSnapshots
+	}
+	return snapshotter.Snapshot()
+}
+
+// Restore replaces the state of the generator with a state returned by Snapshot.
+// Only generators generated in snapshot mode support snapshots, others return ErrNoSnapshots.
+func (it *Generator[T]) Restore(snapshot []byte) error {
+	snapshotter, isSnapshotter := it.iterator.(Snapshotter)
+	if !isSnapshotter {
+		return ErrNoSnapshots
 	}
-	withError := func(err error) bool {
-		it.err = err
-		return false
+	return snapshotter.Restore(snapshot)
+}
+
+// Clone returns a generator that continues the iteration from the current point, independently
+// of this one.
+// The variables of the generator are copied, but the values they point to are shared.
+// Only generators generated in clone mode can be cloned, others return ErrNoClones.
+func (it *Generator[T]) Clone() (Generator[T], error) {
+	cloner, isCloner := it.iterator.(Cloner[T])
+	if !isCloner {
+		return Generator[T]{}, ErrNoClones
 	}
-	exhausted := func() bool { return false }
-	return it.advance(withValue, withError, exhausted)
+	return FromIterator(cloner.Clone()), nil
 }
 
 // MakeGenerator creates a generator with the given advance function.
+// On every call, advance either stores the next value and returns true, or stores the termination
+// error (if any) and returns false.
 // Used by code-generation, and should not generally be used manually.
-func MakeGenerator[T any](advance func(withValue func(value T) bool, withError func(err error) bool, exhausted func() bool) bool) Generator[T] {
+func MakeGenerator[T any](advance func(value *T, err *error) bool) Generator[T] {
 	return Generator[T]{advance: advance}
 }
+
+// FromIterator creates a generator that delegates to the given iterator.
+// Used by code-generation for struct-based state machines, and should not generally be used manually.
+func FromIterator[T any](iterator Iterator[T]) Generator[T] {
+	return Generator[T]{iterator: iterator}
+}
@@ -0,0 +1,36 @@
+package gengen
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+)
+
+// ErrNoSnapshots is returned when snapshotting or restoring a generator that was not generated
+// in snapshot mode.
+var ErrNoSnapshots = errors.New("gengen: generator does not support snapshots")
+
+// Snapshotter is implemented by generators that can persist their state mid-iteration,
+// and resume from it later.
+type Snapshotter interface {
+	// Snapshot returns the serialized state of the generator.
+	Snapshot() ([]byte, error)
+	// Restore replaces the state of the generator with a state returned by Snapshot.
+	Restore(snapshot []byte) error
+}
+
+// EncodeSnapshot serializes the state of a generator using encoding/gob.
+// Used by code-generation, and should not generally be used manually.
+func EncodeSnapshot(state any) ([]byte, error) {
+	var snapshot bytes.Buffer
+	if err := gob.NewEncoder(&snapshot).Encode(state); err != nil {
+		return nil, err
+	}
+	return snapshot.Bytes(), nil
+}
+
+// DecodeSnapshot deserializes the state of a generator returned by EncodeSnapshot.
+// Used by code-generation, and should not generally be used manually.
+func DecodeSnapshot(snapshot []byte, state any) error {
+	return gob.NewDecoder(bytes.NewReader(snapshot)).Decode(state)
+}
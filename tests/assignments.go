@@ -0,0 +1,121 @@
+//go:build gengen
+
+package tests
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/tmr232/gengen"
+)
+
+func Redeclarations(inputs []string) gengen.Generator[int] {
+	value, err := 0, error(nil)
+	for _, input := range inputs {
+		value, err = strconv.Atoi(input)
+		gengen.Yield(value)
+		// The loop body is a new scope, so both parsed and a new err are declared here.
+		parsed, err := strconv.Atoi(input + "0")
+		if err != nil {
+			return err
+		}
+		gengen.Yield(parsed)
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// SameScopeRedeclarations redeclares err in the scope it was declared in, so err is reused
+// across the yield while b is declared.
+func SameScopeRedeclarations(first, second string) gengen.Generator[int] {
+	a, err := strconv.Atoi(first)
+	gengen.Yield(a)
+	b, err := strconv.Atoi(second)
+	gengen.Yield(b)
+	return err
+}
+
+func CommaOk(m map[string]int, keys []string) gengen.Generator[string] {
+	for _, key := range keys {
+		value, ok := m[key]
+		gengen.Yield(key + "=" + strconv.Itoa(value) + ":" + strconv.FormatBool(ok))
+	}
+	c := make(chan int, 1)
+	c <- 1
+	close(c)
+	for {
+		value, ok := <-c
+		gengen.Yield(strconv.Itoa(value) + ":" + strconv.FormatBool(ok))
+		if !ok {
+			break
+		}
+	}
+	var x any = "a"
+	text, isString := x.(string)
+	gengen.Yield(text + ":" + strconv.FormatBool(isString))
+	return nil
+}
+
+func BlankAssignments() gengen.Generator[int] {
+	_, b := 1, 2
+	_ = b
+	var _, c = 3, 4
+	var d, _ = 5, 6
+	gengen.Yield(b)
+	gengen.Yield(c)
+	gengen.Yield(d)
+	return nil
+}
+
+func OpAssignments(values []int) gengen.Generator[int] {
+	calls := 0
+	index := func() int {
+		calls++
+		return 1
+	}
+	values[index()] += 10
+	gengen.Yield(values[1])
+	values[index()] <<= 2
+	gengen.Yield(values[1])
+	gengen.Yield(calls)
+	total := 0
+	for _, value := range values {
+		total += value
+		total %= 100
+		gengen.Yield(total)
+	}
+	return nil
+}
+
+func multiple() (int, string, error) {
+	return 1, "a", errors.New("e")
+}
+
+func MultipleValues() gengen.Generator[string] {
+	var n, s, err = multiple()
+	gengen.Yield(strconv.Itoa(n) + s + err.Error())
+	a, b := 1, 2
+	a, b = b, a
+	gengen.Yield(strconv.Itoa(a) + strconv.Itoa(b))
+	return nil
+}
+
+func ZeroValues(n int) gengen.Generator[string] {
+	for i := 0; i < n; i++ {
+		var count int
+		var text string
+		var values []int
+		var point struct{ X int }
+		count++
+		text += "a"
+		values = append(values, i)
+		point.X += i
+		// Iterations without a yield run within the same call to the advance function.
+		if i%2 == 1 {
+			gengen.Yield(strconv.Itoa(count) + text + strconv.Itoa(len(values)) + strconv.Itoa(point.X))
+		}
+	}
+	return nil
+}
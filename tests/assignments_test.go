@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignments(t *testing.T) {
+	t.Run("Redeclarations", func(t *testing.T) {
+		want := []int{1, 10, 2, 20}
+		if got := ToSlice(Redeclarations([]string{"1", "2"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Redeclarations() = %v, want %v", got, want)
+		}
+		gen := Redeclarations([]string{"1", "x"})
+		for gen.Next() {
+		}
+		if gen.Error() == nil {
+			t.Errorf("Redeclarations() did not fail on invalid input")
+		}
+	})
+	t.Run("SameScopeRedeclarations", func(t *testing.T) {
+		gen := SameScopeRedeclarations("x", "2")
+		var got []int
+		for gen.Next() {
+			got = append(got, gen.Value())
+		}
+		if want := []int{0, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("SameScopeRedeclarations() = %v, want %v", got, want)
+		}
+		if gen.Error() != nil {
+			t.Errorf("SameScopeRedeclarations() failed on a reassigned error: %v", gen.Error())
+		}
+		gen = SameScopeRedeclarations("1", "x")
+		for gen.Next() {
+		}
+		if gen.Error() == nil {
+			t.Errorf("SameScopeRedeclarations() did not fail on invalid input")
+		}
+	})
+	t.Run("CommaOk", func(t *testing.T) {
+		want := []string{"a=1:true", "b=0:false", "1:true", "0:false", "a:true"}
+		if got := ToSlice(CommaOk(map[string]int{"a": 1}, []string{"a", "b"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("CommaOk() = %v, want %v", got, want)
+		}
+	})
+	t.Run("BlankAssignments", func(t *testing.T) {
+		want := []int{2, 4, 5}
+		if got := ToSlice(BlankAssignments()); !reflect.DeepEqual(got, want) {
+			t.Errorf("BlankAssignments() = %v, want %v", got, want)
+		}
+	})
+	t.Run("OpAssignments", func(t *testing.T) {
+		want := []int{12, 48, 2, 1, 49, 52}
+		if got := ToSlice(OpAssignments([]int{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("OpAssignments() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MultipleValues", func(t *testing.T) {
+		want := []string{"1ae", "21"}
+		if got := ToSlice(MultipleValues()); !reflect.DeepEqual(got, want) {
+			t.Errorf("MultipleValues() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ZeroValues", func(t *testing.T) {
+		want := []string{"1a11", "1a13"}
+		if got := ToSlice(ZeroValues(4)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ZeroValues() = %v, want %v", got, want)
+		}
+	})
+}
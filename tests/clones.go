@@ -0,0 +1,49 @@
+//go:build gengen
+
+package tests
+
+import "github.com/tmr232/gengen"
+
+//gengen:clone
+func Tokens(text []rune) gengen.Generator[string] {
+	start := 0
+	for i, c := range text {
+		if c == ' ' {
+			if i > start {
+				gengen.Yield(string(text[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		gengen.Yield(string(text[start:]))
+	}
+	return nil
+}
+
+//gengen:clone
+func Accumulate(values []int) gengen.Generator[int] {
+	total := 0
+	for _, value := range values {
+		// value is captured by the closure, so it is held by a pointer.
+		func() { total += value }()
+		gengen.Yield(total)
+	}
+	return nil
+}
+
+//gengen:clone
+func SortedKeys[V any](m map[string]V) gengen.Generator[string] {
+	for key := range gengen.Sorted(m) {
+		gengen.Yield(key)
+	}
+	return nil
+}
+
+//gengen:clone
+func CloneName(n int) gengen.Generator[int] {
+	for Clone := 0; Clone < n; Clone++ {
+		gengen.Yield(Clone)
+	}
+	return nil
+}
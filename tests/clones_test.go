@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tmr232/gengen"
+)
+
+// forked advances a generator a few steps, clones it, and exhausts the clone before the original.
+func forked[T any](t *testing.T, steps int, gen gengen.Generator[T]) (prefix []T, clone []T, original []T) {
+	for i := 0; i < steps && gen.Next(); i++ {
+		prefix = append(prefix, gen.Value())
+	}
+	cloned, err := gen.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone = ToSlice(cloned)
+	original = ToSlice(gen)
+	return
+}
+
+func TestClones(t *testing.T) {
+	t.Run("Tokens", func(t *testing.T) {
+		prefix, clone, original := forked(t, 2, Tokens([]rune("a bb  ccc d")))
+		if want := []string{"a", "bb"}; !reflect.DeepEqual(prefix, want) {
+			t.Errorf("Tokens() = %v, want %v", prefix, want)
+		}
+		want := []string{"ccc", "d"}
+		if !reflect.DeepEqual(clone, want) {
+			t.Errorf("Tokens() clone = %v, want %v", clone, want)
+		}
+		if !reflect.DeepEqual(original, want) {
+			t.Errorf("Tokens() original = %v, want %v", original, want)
+		}
+	})
+	t.Run("Accumulate", func(t *testing.T) {
+		_, clone, original := forked(t, 2, Accumulate([]int{1, 2, 3, 4}))
+		want := []int{6, 10}
+		if !reflect.DeepEqual(clone, want) {
+			t.Errorf("Accumulate() clone = %v, want %v", clone, want)
+		}
+		if !reflect.DeepEqual(original, want) {
+			t.Errorf("Accumulate() original = %v, want %v", original, want)
+		}
+	})
+	t.Run("SortedKeys", func(t *testing.T) {
+		_, clone, original := forked(t, 1, SortedKeys(map[string]int{"b": 2, "a": 1, "c": 3}))
+		want := []string{"b", "c"}
+		if !reflect.DeepEqual(clone, want) {
+			t.Errorf("SortedKeys() clone = %v, want %v", clone, want)
+		}
+		if !reflect.DeepEqual(original, want) {
+			t.Errorf("SortedKeys() original = %v, want %v", original, want)
+		}
+	})
+	t.Run("CloneName", func(t *testing.T) {
+		_, clone, original := forked(t, 1, CloneName(3))
+		want := []int{1, 2}
+		if !reflect.DeepEqual(clone, want) {
+			t.Errorf("CloneName() clone = %v, want %v", clone, want)
+		}
+		if !reflect.DeepEqual(original, want) {
+			t.Errorf("CloneName() original = %v, want %v", original, want)
+		}
+	})
+	t.Run("Lookahead", func(t *testing.T) {
+		gen := Tokens([]rune("x y z"))
+		gen.Next()
+		lookahead, err := gen.Clone()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookahead.Next()
+		if lookahead.Value() != "y" || gen.Value() != "x" {
+			t.Errorf("Value() = %q, %q, want %q, %q", gen.Value(), lookahead.Value(), "x", "y")
+		}
+	})
+	t.Run("NotCloned", func(t *testing.T) {
+		gen := Issue4(3)
+		if _, err := gen.Clone(); !errors.Is(err, gengen.ErrNoClones) {
+			t.Errorf("Clone() error = %v, want %v", err, gengen.ErrNoClones)
+		}
+	})
+}
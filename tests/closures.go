@@ -0,0 +1,91 @@
+//go:build gengen
+
+package tests
+
+import (
+	"github.com/tmr232/gengen"
+)
+
+// CapturedLoopVariables yields closures capturing loop variables, which must each see the value
+// of their own iteration.
+func CapturedLoopVariables() gengen.Generator[func() int] {
+	for i := 0; i < 3; i++ {
+		gengen.Yield(func() int { return i })
+	}
+	for _, value := range []int{10, 20, 30} {
+		gengen.Yield(func() int { return value })
+	}
+	for i := 0; i < 3; i++ {
+		square := i * i
+		gengen.Yield(func() int { return square })
+	}
+	return nil
+}
+
+// LoopVariableAddresses yields the addresses of loop variables.
+func LoopVariableAddresses() gengen.Generator[*int] {
+	for i := 0; i < 3; i++ {
+		gengen.Yield(&i)
+	}
+	return nil
+}
+
+// Goroutines starts a goroutine per iteration, each sending its own loop variable.
+func Goroutines() gengen.Generator[int] {
+	results := make(chan int)
+	for i := 0; i < 3; i++ {
+		go func() { results <- i }()
+		gengen.Yield(<-results)
+	}
+	return nil
+}
+
+// MutatedInClosure modifies a loop variable from within a closure, which affects the loop.
+func MutatedInClosure() gengen.Generator[int] {
+	for i := 0; i < 10; i++ {
+		skip := func() { i++ }
+		gengen.Yield(i)
+		skip()
+	}
+	return nil
+}
+
+type holder struct {
+	N     int
+	Array [1]int
+}
+
+// Address returns the address of the holder, letting it escape through a pointer method.
+func (h *holder) Address() *int {
+	return &h.N
+}
+
+// PartialAddresses yields the addresses of parts of loop variables, which take the address of
+// the variables themselves.
+func PartialAddresses() gengen.Generator[*int] {
+	for i := 0; i < 3; i++ {
+		var s holder
+		s.N = i
+		gengen.Yield(&s.N)
+		_ = s.N
+	}
+	for i := 0; i < 3; i++ {
+		gengen.Yield(&(i))
+	}
+	for i := 0; i < 3; i++ {
+		var a [1]int
+		a[0] = i
+		gengen.Yield(&a[0])
+	}
+	for i := 0; i < 3; i++ {
+		var s holder
+		s.Array[0] = i
+		gengen.Yield(&s.Array[:][0])
+	}
+	for i := 0; i < 3; i++ {
+		var s struct{ Inner holder }
+		s.Inner.N = i
+		gengen.Yield(s.Inner.Address())
+	}
+	return nil
+}
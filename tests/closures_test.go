@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClosures(t *testing.T) {
+	t.Run("CapturedLoopVariables", func(t *testing.T) {
+		var got []int
+		for _, f := range ToSlice(CapturedLoopVariables()) {
+			got = append(got, f())
+		}
+		want := []int{0, 1, 2, 10, 20, 30, 0, 1, 4}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("CapturedLoopVariables() = %v, want %v", got, want)
+		}
+	})
+	t.Run("LoopVariableAddresses", func(t *testing.T) {
+		var got []int
+		for _, p := range ToSlice(LoopVariableAddresses()) {
+			got = append(got, *p)
+		}
+		want := []int{0, 1, 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("LoopVariableAddresses() = %v, want %v", got, want)
+		}
+	})
+	t.Run("PartialAddresses", func(t *testing.T) {
+		var got []int
+		for _, p := range ToSlice(PartialAddresses()) {
+			got = append(got, *p)
+		}
+		want := []int{0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2, 0, 1, 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PartialAddresses() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Goroutines", func(t *testing.T) {
+		want := []int{0, 1, 2}
+		if got := ToSlice(Goroutines()); !reflect.DeepEqual(got, want) {
+			t.Errorf("Goroutines() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MutatedInClosure", func(t *testing.T) {
+		want := []int{0, 2, 4, 6, 8}
+		if got := ToSlice(MutatedInClosure()); !reflect.DeepEqual(got, want) {
+			t.Errorf("MutatedInClosure() = %v, want %v", got, want)
+		}
+	})
+}
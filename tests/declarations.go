@@ -0,0 +1,70 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+
+	"github.com/tmr232/gengen"
+)
+
+func LocalTypes(names []string) gengen.Generator[string] {
+	type entry struct {
+		index int
+		name  string
+	}
+	var entries []entry
+	for i, name := range names {
+		entries = append(entries, entry{index: i, name: name})
+	}
+	for _, e := range entries {
+		gengen.Yield(fmt.Sprintf("%d:%s", e.index, e.name))
+	}
+	return nil
+}
+
+func ShadowedLocalTypes() gengen.Generator[string] {
+	type value int
+	first := value(1)
+	{
+		type value string
+		second := value("a")
+		gengen.Yield(fmt.Sprint(first, second))
+		second += "b"
+		gengen.Yield(fmt.Sprint(first, second))
+	}
+	first += 2
+	gengen.Yield(fmt.Sprint(first))
+	return nil
+}
+
+func LocalConstants() gengen.Generator[int] {
+	type color int
+	const (
+		red color = iota
+		green
+		blue
+	)
+	// Untyped constants keep their arbitrary precision.
+	const huge = 1 << 100
+	for _, c := range []color{blue, red, green} {
+		gengen.Yield(int(c))
+	}
+	gengen.Yield(huge >> 98)
+	return nil
+}
+
+func GenericLocalTypes[T any](values []T) gengen.Generator[string] {
+	type box struct {
+		value T
+	}
+	type boxes []box
+	var all boxes
+	for _, value := range values {
+		all = append(all, box{value})
+	}
+	for _, b := range all {
+		gengen.Yield(fmt.Sprint(b.value))
+	}
+	return nil
+}
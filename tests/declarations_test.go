@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeclarations(t *testing.T) {
+	t.Run("LocalTypes", func(t *testing.T) {
+		want := []string{"0:a", "1:b"}
+		if got := ToSlice(LocalTypes([]string{"a", "b"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("LocalTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ShadowedLocalTypes", func(t *testing.T) {
+		want := []string{"1a", "1ab", "3"}
+		if got := ToSlice(ShadowedLocalTypes()); !reflect.DeepEqual(got, want) {
+			t.Errorf("ShadowedLocalTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("LocalConstants", func(t *testing.T) {
+		want := []int{2, 0, 1, 4}
+		if got := ToSlice(LocalConstants()); !reflect.DeepEqual(got, want) {
+			t.Errorf("LocalConstants() = %v, want %v", got, want)
+		}
+	})
+	t.Run("GenericLocalTypes", func(t *testing.T) {
+		want := []string{"1", "2"}
+		if got := ToSlice(GenericLocalTypes([]int{1, 2})); !reflect.DeepEqual(got, want) {
+			t.Errorf("GenericLocalTypes() = %v, want %v", got, want)
+		}
+	})
+}
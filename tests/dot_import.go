@@ -0,0 +1,12 @@
+//go:build gengen
+
+package tests
+
+import . "github.com/tmr232/gengen"
+
+func DotImport(n int) Generator[int] {
+	for i := 0; i < n; i++ {
+		Yield(i)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmr232/gengen"
+)
+
+// Precedence yields expressions whose meaning depends on their parentheses.
+func Precedence(a, b, c int) gengen.Generator[int] {
+	gengen.Yield((a + b) * c)
+	gengen.Yield(a - (b - c))
+	gengen.Yield(-(-a))
+	gengen.Yield(^(-b))
+	gengen.Yield(c / (a * b))
+	return nil
+}
+
+type Point struct {
+	X, Y int
+}
+
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func sum(values ...int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+func Slices(values []int) gengen.Generator[[]int] {
+	gengen.Yield(values[1:3])
+	gengen.Yield(values[:1])
+	gengen.Yield(values[2:])
+	gengen.Yield(values[0:2:2])
+	return nil
+}
+
+func Pointers(value int) gengen.Generator[int] {
+	p := &value
+	gengen.Yield(*p)
+	*p = 2
+	gengen.Yield(value)
+	return nil
+}
+
+func KeyValues() gengen.Generator[string] {
+	gengen.Yield(fmt.Sprint(Point{X: 1, Y: 2}))
+	gengen.Yield(fmt.Sprint(map[string]int{"a": 1}))
+	gengen.Yield(fmt.Sprint([]string{2: "c", 0: "a"}))
+	return nil
+}
+
+func IndexLists() gengen.Generator[string] {
+	gengen.Yield(fmt.Sprint(Entry[string, int]{"a", 1}))
+	gengen.Yield(fmt.Sprint(Entry[int, []bool]{Key: 2}))
+	return nil
+}
+
+func MapTypes() gengen.Generator[int] {
+	counts := make(map[string]int)
+	counts["a"]++
+	gengen.Yield(len(counts))
+	gengen.Yield(len(map[int]map[int]bool{1: {}, 2: nil}))
+	return nil
+}
+
+func FuncTypes(values []int) gengen.Generator[int] {
+	var apply func(func(int) int) []int
+	apply = func(f func(int) int) []int {
+		var result []int
+		for _, value := range values {
+			result = append(result, f(value))
+		}
+		return result
+	}
+	for _, value := range apply(func(x int) int { return x * 10 }) {
+		gengen.Yield(value)
+	}
+	return nil
+}
+
+func InterfaceTypes(values []any) gengen.Generator[string] {
+	for _, value := range values {
+		if stringer, ok := value.(interface{ String() string }); ok {
+			gengen.Yield(stringer.String())
+		}
+	}
+	return nil
+}
+
+func StructTypes() gengen.Generator[string] {
+	point := struct{ X, Y int }{1, 2}
+	gengen.Yield(fmt.Sprint(point))
+	gengen.Yield(fmt.Sprint(struct {
+		Name string
+	}{Name: "a"}))
+	return nil
+}
+
+func Ellipses(values []int) gengen.Generator[int] {
+	gengen.Yield(sum(values...))
+	gengen.Yield(sum(append([]int{10}, values...)...))
+	gengen.Yield(len([...]string{"a", "b", "c"}))
+	return nil
+}
+
+func TypeAsserts(value any) gengen.Generator[string] {
+	gengen.Yield(value.(fmt.Stringer).String())
+	_, isInt := value.(int)
+	gengen.Yield(fmt.Sprint(isInt))
+	return nil
+}
+
+func ChanTypes() gengen.Generator[int] {
+	c := make(chan int, 2)
+	var send chan<- int = c
+	var receive <-chan int = c
+	send <- 1
+	send <- 2
+	gengen.Yield(<-receive)
+	gengen.Yield(<-c)
+	return nil
+}
+
+func Literals() gengen.Generator[string] {
+	gengen.Yield(fmt.Sprint(0x10, 0o10, 1e3, 'a', 2i))
+	gengen.Yield(`raw\n` + "quoted\n")
+	gengen.Yield(strings.Repeat("ab", 2))
+	return nil
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrecedence(t *testing.T) {
+	want := []int{(1 + 2) * 12, 1 - (2 - 12), 1, ^(-2), 12 / (1 * 2)}
+	got := ToSlice(Precedence(1, 2, 12))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Precedence() = %v, want %v", got, want)
+	}
+}
+
+type name string
+
+func (n name) String() string {
+	return "name " + string(n)
+}
+
+func TestExpressions(t *testing.T) {
+	t.Run("Slices", func(t *testing.T) {
+		want := [][]int{{2, 3}, {1}, {3, 4}, {1, 2}}
+		if got := ToSlice(Slices([]int{1, 2, 3, 4})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Slices() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Pointers", func(t *testing.T) {
+		want := []int{1, 2}
+		if got := ToSlice(Pointers(1)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Pointers() = %v, want %v", got, want)
+		}
+	})
+	t.Run("KeyValues", func(t *testing.T) {
+		want := []string{"{1 2}", "map[a:1]", "[a  c]"}
+		if got := ToSlice(KeyValues()); !reflect.DeepEqual(got, want) {
+			t.Errorf("KeyValues() = %v, want %v", got, want)
+		}
+	})
+	t.Run("IndexLists", func(t *testing.T) {
+		want := []string{"{a 1}", "{2 []}"}
+		if got := ToSlice(IndexLists()); !reflect.DeepEqual(got, want) {
+			t.Errorf("IndexLists() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MapTypes", func(t *testing.T) {
+		want := []int{1, 2}
+		if got := ToSlice(MapTypes()); !reflect.DeepEqual(got, want) {
+			t.Errorf("MapTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("FuncTypes", func(t *testing.T) {
+		want := []int{10, 20}
+		if got := ToSlice(FuncTypes([]int{1, 2})); !reflect.DeepEqual(got, want) {
+			t.Errorf("FuncTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("InterfaceTypes", func(t *testing.T) {
+		want := []string{"name a", "name b"}
+		if got := ToSlice(InterfaceTypes([]any{name("a"), 1, name("b")})); !reflect.DeepEqual(got, want) {
+			t.Errorf("InterfaceTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("StructTypes", func(t *testing.T) {
+		want := []string{"{1 2}", "{a}"}
+		if got := ToSlice(StructTypes()); !reflect.DeepEqual(got, want) {
+			t.Errorf("StructTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Ellipses", func(t *testing.T) {
+		want := []int{3, 13, 3}
+		if got := ToSlice(Ellipses([]int{1, 2})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Ellipses() = %v, want %v", got, want)
+		}
+	})
+	t.Run("TypeAsserts", func(t *testing.T) {
+		want := []string{"name a", "false"}
+		if got := ToSlice(TypeAsserts(name("a"))); !reflect.DeepEqual(got, want) {
+			t.Errorf("TypeAsserts() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ChanTypes", func(t *testing.T) {
+		want := []int{1, 2}
+		if got := ToSlice(ChanTypes()); !reflect.DeepEqual(got, want) {
+			t.Errorf("ChanTypes() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Literals", func(t *testing.T) {
+		want := []string{"16 8 1000 97 (0+2i)", "raw\\nquoted\n", "abab"}
+		if got := ToSlice(Literals()); !reflect.DeepEqual(got, want) {
+			t.Errorf("Literals() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+
+	"github.com/tmr232/gengen"
+)
+
+func RunningMax[T gengen.Ordered](values []T) gengen.Generator[T] {
+	var max T
+	for i, value := range values {
+		if i == 0 || value > max {
+			max = value
+		}
+		gengen.Yield(max)
+	}
+	return nil
+}
+
+func Clamped[T interface{ ~int | ~float64 }](low, high T, values []T) gengen.Generator[T] {
+	for _, value := range values {
+		if value < low {
+			value = low
+		} else if value > high {
+			value = high
+		}
+		gengen.Yield(value)
+	}
+	return nil
+}
+
+func MapEntries[K comparable, V any](m map[K]V, keys []K) gengen.Generator[string] {
+	for _, key := range keys {
+		if value, ok := m[key]; ok {
+			gengen.Yield(fmt.Sprint(key, "=", value))
+		}
+	}
+	return nil
+}
+
+func MapValues[K comparable, V any](m map[K]V) gengen.Generator[V] {
+	for _, value := range m {
+		gengen.Yield(value)
+	}
+	return nil
+}
+
+func Elements[S ~[]E, E any](s S) gengen.Generator[E] {
+	for i := 0; i < len(s); i++ {
+		gengen.Yield(s[i])
+	}
+	return nil
+}
+
+func Chunks[T any](n int, values []T) gengen.Generator[[]T] {
+	var chunk []T
+	for _, value := range values {
+		chunk = append(chunk, value)
+		if len(chunk) == n {
+			gengen.Yield(chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		gengen.Yield(chunk)
+	}
+	return nil
+}
+
+type Zipped[A, B any] struct {
+	First  A
+	Second B
+}
+
+func Zip[A, B any](as []A, bs []B) gengen.Generator[Zipped[A, B]] {
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		gengen.Yield(Zipped[A, B]{as[i], bs[i]})
+	}
+	return nil
+}
+
+func Flatten[T any](generators ...gengen.Generator[T]) gengen.Generator[T] {
+	for _, generator := range generators {
+		for generator.Next() {
+			gengen.Yield(generator.Value())
+		}
+		if err := generator.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Counts[T comparable](values []T) gengen.Generator[map[T]int] {
+	counts := make(map[T]int)
+	for _, value := range values {
+		counts[value]++
+		snapshot := make(map[T]int, len(counts))
+		for k, v := range counts {
+			snapshot[k] = v
+		}
+		gengen.Yield(snapshot)
+	}
+	return nil
+}
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(item T) {
+	s.items = append(s.items, item)
+}
+
+func (s *Stack[E]) Popped() gengen.Generator[E] {
+	for len(s.items) > 0 {
+		top := s.items[len(s.items)-1]
+		s.items = s.items[:len(s.items)-1]
+		gengen.Yield(top)
+	}
+	return nil
+}
+
+func (s Stack[T]) Converted(convert func(T) string) gengen.Generator[string] {
+	for _, item := range s.items {
+		gengen.Yield(convert(item))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type celsius float64
+
+type ids []int
+
+func TestGenerics(t *testing.T) {
+	t.Run("RunningMax", func(t *testing.T) {
+		want := []int{3, 3, 4, 4, 5}
+		if got := ToSlice(RunningMax([]int{3, 1, 4, 1, 5})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RunningMax() = %v, want %v", got, want)
+		}
+		wantStrings := []string{"b", "b", "c"}
+		if got := ToSlice(RunningMax([]string{"b", "a", "c"})); !reflect.DeepEqual(got, wantStrings) {
+			t.Errorf("RunningMax() = %v, want %v", got, wantStrings)
+		}
+	})
+	t.Run("Clamped", func(t *testing.T) {
+		want := []celsius{0, 12.5, 100}
+		if got := ToSlice(Clamped[celsius](0, 100, []celsius{-5, 12.5, 200})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Clamped() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MapEntries", func(t *testing.T) {
+		want := []string{"b=2", "a=1"}
+		if got := ToSlice(MapEntries(map[string]int{"a": 1, "b": 2}, []string{"b", "c", "a"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("MapEntries() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MapValues", func(t *testing.T) {
+		want := []string{"one", "three", "two"}
+		got := ToSlice(MapValues(map[int]string{1: "one", 2: "two", 3: "three"}))
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("MapValues() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Elements", func(t *testing.T) {
+		want := []int{1, 2, 3}
+		if got := ToSlice(Elements(ids{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Elements() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Chunks", func(t *testing.T) {
+		want := [][]int{{1, 2}, {3, 4}, {5}}
+		if got := ToSlice(Chunks(2, []int{1, 2, 3, 4, 5})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Chunks() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Zip", func(t *testing.T) {
+		want := []Zipped[int, string]{{1, "a"}, {2, "b"}}
+		if got := ToSlice(Zip([]int{1, 2, 3}, []string{"a", "b"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Zip() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Flatten", func(t *testing.T) {
+		want := []int{1, 2, 3, 4}
+		if got := ToSlice(Flatten(Elements([]int{1, 2}), Elements([]int{3, 4}))); !reflect.DeepEqual(got, want) {
+			t.Errorf("Flatten() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Counts", func(t *testing.T) {
+		want := []map[string]int{{"a": 1}, {"a": 1, "b": 1}, {"a": 2, "b": 1}}
+		if got := ToSlice(Counts([]string{"a", "b", "a"})); !reflect.DeepEqual(got, want) {
+			t.Errorf("Counts() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Methods", func(t *testing.T) {
+		var stack Stack[int]
+		stack.Push(1)
+		stack.Push(2)
+		want := []string{"1", "2"}
+		if got := ToSlice(stack.Converted(func(i int) string { return fmt.Sprint(i) })); !reflect.DeepEqual(got, want) {
+			t.Errorf("Converted() = %v, want %v", got, want)
+		}
+		wantPopped := []int{2, 1}
+		if got := ToSlice(stack.Popped()); !reflect.DeepEqual(got, wantPopped) {
+			t.Errorf("Popped() = %v, want %v", got, wantPopped)
+		}
+	})
+}
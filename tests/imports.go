@@ -0,0 +1,16 @@
+//go:build gengen
+
+package tests
+
+import (
+	"errors"
+	gen "github.com/tmr232/gengen"
+)
+
+// AliasedImport uses the gengen package under a different name.
+// The `errors` import is only used after the return statement, where yields are dropped.
+func AliasedImport() gen.Generator[int] {
+	gen.Yield(1)
+	return nil
+	gen.Yield(len(errors.New("unreachable").Error()))
+}
@@ -0,0 +1,22 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAliasedImport(t *testing.T) {
+	want := []int{1}
+	got := ToSlice(AliasedImport())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AliasedImport() = %v, want %v", got, want)
+	}
+}
+
+func TestDotImport(t *testing.T) {
+	want := []int{0, 1, 2}
+	got := ToSlice(DotImport(3))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DotImport() = %v, want %v", got, want)
+	}
+}
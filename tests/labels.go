@@ -0,0 +1,67 @@
+//go:build gengen
+
+package tests
+
+import "github.com/tmr232/gengen"
+
+func GotoLoop(n int) gengen.Generator[int] {
+	i := 0
+loop:
+	if i >= n {
+		goto done
+	}
+	gengen.Yield(i)
+	i++
+	goto loop
+done:
+	return nil
+}
+
+func LabeledLoops(rows [][]int) gengen.Generator[int] {
+outer:
+	for _, row := range rows {
+		for i := 0; i < len(row); i++ {
+			if row[i] < 0 {
+				continue outer
+			}
+			if row[i] == 0 {
+				break outer
+			}
+			gengen.Yield(row[i])
+		}
+	}
+	return nil
+}
+
+func GotoAfterReturn() gengen.Generator[int] {
+	goto start
+end:
+	gengen.Yield(2)
+	return nil
+start:
+	gengen.Yield(1)
+	goto end
+}
+
+// GeneratedLabelNames uses labels named like the labels of the generated code.
+func GeneratedLabelNames() gengen.Generator[int] {
+	goto __Next1
+__Next0:
+	gengen.Yield(2)
+	return nil
+__Next1:
+	gengen.Yield(1)
+	goto __Next0
+}
+
+func GotoCaptures(n int) gengen.Generator[func() int] {
+	i := 0
+loop:
+	value := i
+	gengen.Yield(func() int { return value })
+	i++
+	if i < n {
+		goto loop
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	t.Run("GotoLoop", func(t *testing.T) {
+		want := []int{0, 1, 2}
+		if got := ToSlice(GotoLoop(3)); !reflect.DeepEqual(got, want) {
+			t.Errorf("GotoLoop() = %v, want %v", got, want)
+		}
+	})
+	t.Run("LabeledLoops", func(t *testing.T) {
+		want := []int{1, 2, 3, 4}
+		if got := ToSlice(LabeledLoops([][]int{{1, 2}, {3, -1, 9}, {4, 0, 9}, {9}})); !reflect.DeepEqual(got, want) {
+			t.Errorf("LabeledLoops() = %v, want %v", got, want)
+		}
+	})
+	t.Run("GotoAfterReturn", func(t *testing.T) {
+		want := []int{1, 2}
+		if got := ToSlice(GotoAfterReturn()); !reflect.DeepEqual(got, want) {
+			t.Errorf("GotoAfterReturn() = %v, want %v", got, want)
+		}
+	})
+	t.Run("GeneratedLabelNames", func(t *testing.T) {
+		want := []int{1, 2}
+		if got := ToSlice(GeneratedLabelNames()); !reflect.DeepEqual(got, want) {
+			t.Errorf("GeneratedLabelNames() = %v, want %v", got, want)
+		}
+	})
+	t.Run("GotoCaptures", func(t *testing.T) {
+		want := []int{0, 1, 2}
+		var got []int
+		for _, f := range ToSlice(GotoCaptures(3)) {
+			got = append(got, f())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GotoCaptures() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,15 @@
+//go:build gengen
+
+package tests
+
+import "github.com/tmr232/gengen"
+
+func PanicOnNegative(values []int) gengen.Generator[int] {
+	for _, value := range values {
+		if value < 0 {
+			panic("negative value")
+		}
+		gengen.Yield(value)
+	}
+	return nil
+}
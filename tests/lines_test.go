@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// sourceLine returns the 1-based number of the first line in file containing text.
+func sourceLine(t *testing.T, file string, text string) int {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(string(src), "\n") {
+		if strings.Contains(line, text) {
+			return i + 1
+		}
+	}
+	t.Fatalf("%q not found in %s", text, file)
+	return 0
+}
+
+func TestPanicPointsToGeneratorSource(t *testing.T) {
+	wantLine := sourceLine(t, "lines.go", `panic("negative value")`)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic.")
+		}
+		pcs := make([]uintptr, 32)
+		frames := runtime.CallersFrames(pcs[:runtime.Callers(0, pcs)])
+		for {
+			frame, more := frames.Next()
+			if strings.Contains(frame.Function, "PanicOnNegative") {
+				if filepath.Base(frame.File) != "lines.go" || frame.Line != wantLine {
+					t.Errorf("Panic at %s:%d, want lines.go:%d", filepath.Base(frame.File), frame.Line, wantLine)
+				}
+				return
+			}
+			if !more {
+				break
+			}
+		}
+		t.Error("PanicOnNegative is not in the stack trace.")
+	}()
+
+	ToSlice(PanicOnNegative([]int{1, -1}))
+}
@@ -0,0 +1,21 @@
+//go:build gengen
+
+package tests
+
+import (
+	"github.com/tmr232/gengen"
+)
+
+// WeightedSums yields the running sum of the halved squares of the weighted values.
+// Only the sum is kept between yields, everything else is a temporary.
+func WeightedSums(values []int, weight int) gengen.Generator[int] {
+	sum := 0
+	for _, value := range values {
+		weighted := value * weight
+		squared := weighted * weighted
+		halved := squared / 2
+		sum += halved
+		gengen.Yield(sum)
+	}
+	return nil
+}
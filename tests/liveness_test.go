@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"github.com/tmr232/gengen"
+	"reflect"
+	"testing"
+)
+
+// weightedSumsHoisted is WeightedSums as it was generated before liveness analysis, with all the
+// variables hoisted out of the advance function.
+func weightedSumsHoisted(values []int, weight int) gengen.Generator[int] {
+	var sum int
+	var value int
+	var weighted int
+	var squared int
+	var halved int
+	var __sliceAdapter1 *gengen.SliceAdapter[int]
+	__next := 0
+	return gengen.MakeGenerator[int](
+		func(__value *int, __err *error) bool {
+			switch __next {
+			case 0:
+				goto __Next0
+			case 1:
+				goto __Next1
+			}
+		__Next0:
+			sum = 0
+			__sliceAdapter1 = gengen.NewSliceAdapter[int](values)
+		__Head1:
+			if __sliceAdapter1.Next() {
+				goto __Body1
+			} else {
+				goto __After1
+			}
+		__Body1:
+			_, value = __sliceAdapter1.Value()
+			weighted = value * weight
+			squared = weighted * weighted
+			halved = squared / 2
+			sum += halved
+			__next = 1
+			*__value = sum
+			return true
+		__Next1:
+			goto __Head1
+		__After1:
+			return false
+		},
+	)
+}
+
+var weightedSumsValues = []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+func drain(gen gengen.Generator[int]) (sum int) {
+	for gen.Next() {
+		sum += gen.Value()
+	}
+	return sum
+}
+
+func TestWeightedSums(t *testing.T) {
+	want := ToSlice(weightedSumsHoisted(weightedSumsValues, 3))
+	if got := ToSlice(WeightedSums(weightedSumsValues, 3)); !reflect.DeepEqual(got, want) {
+		t.Errorf("WeightedSums() = %v, want %v", got, want)
+	}
+}
+
+func TestLivenessReducesAllocations(t *testing.T) {
+	hoisted := testing.AllocsPerRun(100, func() { drain(weightedSumsHoisted(weightedSumsValues, 3)) })
+	got := testing.AllocsPerRun(100, func() { drain(WeightedSums(weightedSumsValues, 3)) })
+	if got >= hoisted {
+		t.Errorf("WeightedSums allocates %v times per run, want less than %v", got, hoisted)
+	}
+}
+
+func BenchmarkWeightedSums(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(WeightedSums(weightedSumsValues, 3))
+	}
+}
+
+func BenchmarkWeightedSumsHoisted(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		drain(weightedSumsHoisted(weightedSumsValues, 3))
+	}
+}
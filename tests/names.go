@@ -0,0 +1,76 @@
+//go:build gengen
+
+package tests
+
+import (
+	"github.com/tmr232/gengen"
+	"strings"
+)
+
+var i1 = 100
+
+var total = 10
+
+// SecondLoopVariable has two loop variables named `i`, where the second must not be renamed to
+// the global `i1`.
+func SecondLoopVariable() gengen.Generator[int] {
+	for i := 0; i < 2; i++ {
+		gengen.Yield(i)
+	}
+	for i := 0; i < 2; i++ {
+		gengen.Yield(i + i1)
+	}
+	return nil
+}
+
+func ShadowsGlobal() gengen.Generator[int] {
+	gengen.Yield(total)
+	{
+		total := 1
+		gengen.Yield(total)
+	}
+	gengen.Yield(total)
+	return nil
+}
+
+func ShadowsImport() gengen.Generator[string] {
+	{
+		strings := []string{"a", "b"}
+		for _, s := range strings {
+			gengen.Yield(s)
+		}
+	}
+	gengen.Yield(strings.ToUpper("c"))
+	return nil
+}
+
+func ShadowsBuiltin(values []int) gengen.Generator[int] {
+	{
+		len := 5
+		gengen.Yield(len)
+	}
+	gengen.Yield(len(values))
+	return nil
+}
+
+type Tree []int
+
+// Walk and Tree_Walk both base their generated names on `Tree_Walk`.
+//
+//gengen:snapshot
+func (tree Tree) Walk() gengen.Generator[int] {
+	const scale = 1
+	for _, value := range tree {
+		gengen.Yield(value * scale)
+	}
+	return nil
+}
+
+//gengen:snapshot
+func Tree_Walk(tree Tree) gengen.Generator[int] {
+	const scale = 10
+	for _, value := range tree {
+		gengen.Yield(value * scale)
+	}
+	return nil
+}
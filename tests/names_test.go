@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNames(t *testing.T) {
+	t.Run("SecondLoopVariable", func(t *testing.T) {
+		want := []int{0, 1, 100, 101}
+		if got := ToSlice(SecondLoopVariable()); !reflect.DeepEqual(got, want) {
+			t.Errorf("SecondLoopVariable() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ShadowsGlobal", func(t *testing.T) {
+		want := []int{10, 1, 10}
+		if got := ToSlice(ShadowsGlobal()); !reflect.DeepEqual(got, want) {
+			t.Errorf("ShadowsGlobal() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ShadowsImport", func(t *testing.T) {
+		want := []string{"a", "b", "C"}
+		if got := ToSlice(ShadowsImport()); !reflect.DeepEqual(got, want) {
+			t.Errorf("ShadowsImport() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ShadowsBuiltin", func(t *testing.T) {
+		want := []int{5, 3}
+		if got := ToSlice(ShadowsBuiltin([]int{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("ShadowsBuiltin() = %v, want %v", got, want)
+		}
+	})
+	t.Run("GeneratedNames", func(t *testing.T) {
+		tree := Tree{1, 2}
+		want := []int{1, 2, 10, 20}
+		if got := append(ToSlice(tree.Walk()), ToSlice(Tree_Walk(tree))...); !reflect.DeepEqual(got, want) {
+			t.Errorf("Walk() and Tree_Walk() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,109 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+
+	"github.com/tmr232/gengen"
+)
+
+type IDs []int
+
+type Ages map[string]int
+
+type Grid [2][2]int
+
+type IntSlices interface {
+	~[]int
+}
+
+func RangeNamedSlice(ids IDs) gengen.Generator[int] {
+	for i, id := range ids {
+		gengen.Yield(i * id)
+	}
+	return nil
+}
+
+func RangeNamedMap(ages Ages) gengen.Generator[int] {
+	total := 0
+	for _, age := range ages {
+		total += age
+	}
+	gengen.Yield(total)
+	for name := range ages {
+		gengen.Yield(len(name))
+	}
+	return nil
+}
+
+func RangeNamedArray(grid Grid) gengen.Generator[int] {
+	for _, row := range grid {
+		for _, cell := range row {
+			gengen.Yield(cell)
+		}
+	}
+	return nil
+}
+
+// RangeArrayPointer ranges over an array through a pointer, seeing the changes made to it.
+func RangeArrayPointer(array *[3]int) gengen.Generator[int] {
+	for i, value := range array {
+		gengen.Yield(value)
+		if i+1 < len(array) {
+			array[i+1] += value
+		}
+	}
+	return nil
+}
+
+func RangeSliceParam[S ~[]E, E any](s S) gengen.Generator[E] {
+	for _, e := range s {
+		gengen.Yield(e)
+	}
+	return nil
+}
+
+func RangeMapParam[M ~map[K]V, K comparable, V any](m M) gengen.Generator[K] {
+	for k := range m {
+		gengen.Yield(k)
+	}
+	return nil
+}
+
+func RangeConstrainedParam[S IntSlices | IDs](s S) gengen.Generator[int] {
+	for _, i := range s {
+		gengen.Yield(i)
+	}
+	return nil
+}
+
+func RangeSorted(ages Ages) gengen.Generator[string] {
+	for name, age := range gengen.Sorted(ages) {
+		gengen.Yield(fmt.Sprint(name, age))
+	}
+	return nil
+}
+
+func RangeSortedFunc[K comparable, V any](m map[K]V, less func(a, b K) bool) gengen.Generator[K] {
+	for k := range gengen.SortedFunc(m, less) {
+		gengen.Yield(k)
+	}
+	return nil
+}
+
+func RangeDeleting(m map[int]bool) gengen.Generator[int] {
+	for key := range gengen.Sorted(m) {
+		delete(m, key+1)
+		gengen.Yield(key)
+	}
+	for key := range m {
+		for other := range m {
+			if other != key {
+				delete(m, other)
+			}
+		}
+		gengen.Yield(len(m))
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	t.Run("RangeNamedSlice", func(t *testing.T) {
+		want := []int{0, 2, 6}
+		if got := ToSlice(RangeNamedSlice(IDs{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeNamedSlice() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeNamedMap", func(t *testing.T) {
+		want := []int{3, 5, 60}
+		got := ToSlice(RangeNamedMap(Ages{"bob": 20, "alice": 40}))
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeNamedMap() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeNamedArray", func(t *testing.T) {
+		want := []int{1, 2, 3, 4}
+		if got := ToSlice(RangeNamedArray(Grid{{1, 2}, {3, 4}})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeNamedArray() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeArrayPointer", func(t *testing.T) {
+		want := []int{1, 3, 6}
+		if got := ToSlice(RangeArrayPointer(&[3]int{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeArrayPointer() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeSliceParam", func(t *testing.T) {
+		want := []int{1, 2, 3}
+		if got := ToSlice(RangeSliceParam(IDs{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeSliceParam() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeMapParam", func(t *testing.T) {
+		want := []string{"alice", "bob"}
+		got := ToSlice(RangeMapParam(Ages{"bob": 20, "alice": 40}))
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeMapParam() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeConstrainedParam", func(t *testing.T) {
+		want := []int{4, 5}
+		if got := ToSlice(RangeConstrainedParam(IDs{4, 5})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeConstrainedParam() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeSorted", func(t *testing.T) {
+		want := []string{"alice40", "bob20", "carol30"}
+		if got := ToSlice(RangeSorted(Ages{"bob": 20, "carol": 30, "alice": 40})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeSorted() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeSortedFunc", func(t *testing.T) {
+		want := []int{3, 2, 1}
+		descending := func(a, b int) bool { return a > b }
+		if got := ToSlice(RangeSortedFunc(map[int]bool{1: true, 2: true, 3: true}, descending)); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeSortedFunc() = %v, want %v", got, want)
+		}
+	})
+	t.Run("RangeDeleting", func(t *testing.T) {
+		want := []int{1, 3, 5, 1}
+		if got := ToSlice(RangeDeleting(map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true})); !reflect.DeepEqual(got, want) {
+			t.Errorf("RangeDeleting() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+
+	"github.com/tmr232/gengen"
+)
+
+//gengen:snapshot
+func Fibonacci(limit int) gengen.Generator[int] {
+	a, b := 0, 1
+	for a < limit {
+		gengen.Yield(a)
+		a, b = b, a+b
+	}
+	return nil
+}
+
+type Interval struct {
+	Start, Stop int
+}
+
+//gengen:snapshot
+func IntervalValues(intervals []Interval) gengen.Generator[int] {
+	for i, interval := range intervals {
+		for value := interval.Start; value < interval.Stop; value++ {
+			gengen.Yield(i*100 + value)
+		}
+	}
+	return nil
+}
+
+//gengen:snapshot
+func SortedTotals(totals map[string]int) gengen.Generator[string] {
+	sum := 0
+	for name, total := range gengen.Sorted(totals) {
+		sum += total
+		gengen.Yield(name + ":" + fmt.Sprint(sum))
+	}
+	return nil
+}
+
+//gengen:snapshot
+func Repeated[T any](values []T, times int) gengen.Generator[T] {
+	for i := 0; i < times; i++ {
+		for _, value := range values {
+			gengen.Yield(value)
+		}
+	}
+	return nil
+}
+
+//gengen:snapshot
+func (interval Interval) Values() gengen.Generator[int] {
+	for interval.Start < interval.Stop {
+		gengen.Yield(interval.Start)
+		interval.Start++
+	}
+	return nil
+}
+
+//gengen:snapshot
+func MethodNames(Restore int) gengen.Generator[int] {
+	Snapshot := 0
+	for Snapshot < Restore {
+		gengen.Yield(Snapshot)
+		Snapshot++
+	}
+	return nil
+}
+
+//gengen:snapshot
+func Addresses(n int) gengen.Generator[int] {
+	for i := 0; i < n; i++ {
+		p := &i
+		gengen.Yield(*p)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tmr232/gengen"
+)
+
+// resumed advances a generator a few steps, snapshots it, and restores the snapshot into another
+// generator that continues the iteration.
+func resumed[T any](t *testing.T, steps int, gen gengen.Generator[T], other gengen.Generator[T]) (values []T) {
+	for i := 0; i < steps && gen.Next(); i++ {
+		values = append(values, gen.Value())
+	}
+	snapshot, err := gen.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	return append(values, ToSlice(other)...)
+}
+
+func TestSnapshots(t *testing.T) {
+	t.Run("Fibonacci", func(t *testing.T) {
+		want := []int{0, 1, 1, 2, 3, 5, 8}
+		if got := resumed(t, 4, Fibonacci(10), Fibonacci(0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Fibonacci() = %v, want %v", got, want)
+		}
+	})
+	t.Run("IntervalValues", func(t *testing.T) {
+		want := []int{0, 1, 105, 106, 107, 202}
+		intervals := []Interval{{0, 2}, {5, 8}, {2, 3}}
+		if got := resumed(t, 3, IntervalValues(intervals), IntervalValues(nil)); !reflect.DeepEqual(got, want) {
+			t.Errorf("IntervalValues() = %v, want %v", got, want)
+		}
+	})
+	t.Run("SortedTotals", func(t *testing.T) {
+		want := []string{"a:1", "b:3", "c:6"}
+		totals := map[string]int{"c": 3, "a": 1, "b": 2}
+		if got := resumed(t, 2, SortedTotals(totals), SortedTotals(nil)); !reflect.DeepEqual(got, want) {
+			t.Errorf("SortedTotals() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Repeated", func(t *testing.T) {
+		want := []string{"a", "b", "a", "b"}
+		if got := resumed(t, 3, Repeated([]string{"a", "b"}, 2), Repeated[string](nil, 0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Repeated() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Method", func(t *testing.T) {
+		want := []int{1, 2, 3}
+		if got := resumed(t, 1, Interval{1, 4}.Values(), Interval{}.Values()); !reflect.DeepEqual(got, want) {
+			t.Errorf("Values() = %v, want %v", got, want)
+		}
+	})
+	t.Run("MethodNames", func(t *testing.T) {
+		want := []int{0, 1, 2}
+		if got := resumed(t, 1, MethodNames(3), MethodNames(0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("MethodNames() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Addresses", func(t *testing.T) {
+		want := []int{0, 1, 2}
+		if got := resumed(t, 1, Addresses(3), Addresses(0)); !reflect.DeepEqual(got, want) {
+			t.Errorf("Addresses() = %v, want %v", got, want)
+		}
+	})
+	t.Run("Value", func(t *testing.T) {
+		gen := Fibonacci(10)
+		for i := 0; i < 5; i++ {
+			gen.Next()
+		}
+		snapshot, err := gen.Snapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := Fibonacci(0)
+		if err := restored.Restore(snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if restored.Value() != gen.Value() {
+			t.Errorf("Value() = %v, want %v", restored.Value(), gen.Value())
+		}
+	})
+	t.Run("NotSnapshotted", func(t *testing.T) {
+		gen := Issue4(3)
+		if _, err := gen.Snapshot(); !errors.Is(err, gengen.ErrNoSnapshots) {
+			t.Errorf("Snapshot() error = %v, want %v", err, gengen.ErrNoSnapshots)
+		}
+		if err := gen.Restore(nil); !errors.Is(err, gengen.ErrNoSnapshots) {
+			t.Errorf("Restore() error = %v, want %v", err, gengen.ErrNoSnapshots)
+		}
+	})
+}
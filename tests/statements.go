@@ -0,0 +1,50 @@
+//go:build gengen
+
+package tests
+
+import "github.com/tmr232/gengen"
+
+func IfInitYields(values []int) gengen.Generator[int] {
+	for _, value := range values {
+		if gengen.Yield(value); value%2 == 0 {
+			gengen.Yield(value * 10)
+		}
+	}
+	return nil
+}
+
+func ForInitYields(n int) gengen.Generator[int] {
+	i := 0
+	for gengen.Yield(-1); i < n; i++ {
+		gengen.Yield(i)
+	}
+	return nil
+}
+
+func ForPostYields(n int) gengen.Generator[int] {
+	for i := 0; i < n; gengen.Yield(i) {
+		i++
+	}
+	return nil
+}
+
+func ElseIfChain(values []int) gengen.Generator[string] {
+	for _, value := range values {
+		if value < 0 {
+			gengen.Yield("negative")
+		} else if value == 0 {
+			gengen.Yield("zero")
+		} else if half := value / 2; half*2 == value {
+			gengen.Yield("even")
+			if half > 1 {
+				gengen.Yield("big")
+			}
+		} else if value == 1 {
+			gengen.Yield("one")
+		} else {
+			gengen.Yield("odd")
+		}
+		gengen.Yield(".")
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatements(t *testing.T) {
+	t.Run("IfInitYields", func(t *testing.T) {
+		want := []int{1, 2, 20, 3}
+		if got := ToSlice(IfInitYields([]int{1, 2, 3})); !reflect.DeepEqual(got, want) {
+			t.Errorf("IfInitYields() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ForInitYields", func(t *testing.T) {
+		want := []int{-1, 0, 1}
+		if got := ToSlice(ForInitYields(2)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ForInitYields() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ForPostYields", func(t *testing.T) {
+		want := []int{1, 2, 3}
+		if got := ToSlice(ForPostYields(3)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ForPostYields() = %v, want %v", got, want)
+		}
+	})
+	t.Run("ElseIfChain", func(t *testing.T) {
+		want := []string{"negative", ".", "zero", ".", "one", ".", "even", ".", "odd", ".", "even", "big", "."}
+		if got := ToSlice(ElseIfChain([]int{-1, 0, 1, 2, 3, 4})); !reflect.DeepEqual(got, want) {
+			t.Errorf("ElseIfChain() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+//go:build gengen
+
+package tests
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tmr232/gengen"
+)
+
+// TypeNames keeps variables of many kinds of types across yields, so that they are declared
+// with their types in the generated code.
+func TypeNames() gengen.Generator[string] {
+	point := struct {
+		X, Y int
+		Name string `json:"name"`
+	}{1, 2, "p"}
+	sprintf := func(format string, args ...any) (string, error) {
+		return fmt.Sprintf(format, args...), nil
+	}
+	c := make(chan int, 1)
+	var send chan<- int = c
+	var receive <-chan int = c
+	channels := make(chan (<-chan int), 1)
+	builders := map[string]*strings.Builder{"a": {}}
+	pairs := []*gengen.Pair[string, []gengen.Pair[int, bool]]{gengen.NewPair("a", []gengen.Pair[int, bool]{})}
+	var stringer interface {
+		fmt.Stringer
+		Len() int
+	} = builders["a"]
+	// The type of fs is in io/fs, which is not imported by this file.
+	fs := os.DirFS(".")
+	gengen.Yield("start")
+
+	gengen.Yield(fmt.Sprint(point))
+	text, _ := sprintf("%d-%d", 1, 2)
+	gengen.Yield(text)
+	send <- 3
+	channels <- receive
+	gengen.Yield(fmt.Sprint(<-<-channels))
+	builders["a"].WriteString("b")
+	gengen.Yield(stringer.String())
+	gengen.Yield(fmt.Sprint(len(pairs)))
+	gengen.Yield(fmt.Sprint(fs != nil))
+	return nil
+}
@@ -0,0 +1,14 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypeNames(t *testing.T) {
+	want := []string{"start", "{1 2 p}", "1-2", "3", "b", "1", "true"}
+	got := ToSlice(TypeNames())
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeNames() = %v, want %v", got, want)
+	}
+}